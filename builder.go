@@ -1,7 +1,10 @@
 package torm
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"errors"
@@ -14,20 +17,44 @@ type Builder struct {
 	grammar    grammar.Grammar
 	Query      *query.Query
 	Bindings   map[string][]interface{}
+	ctx        context.Context
+	omitBlank  bool
 }
 
 type Binding []interface{}
 
+// BatchResult The result of a chunked bulk insert, the ids are the ones of the
+// first and last inserted rows, assuming the ids of a statement are consecutive.
+type BatchResult struct {
+	RowsAffected  int64
+	FirstInsertId int64
+	LastInsertId  int64
+}
+
+// The options of the pessimistic locks.
+const (
+	// LockNoWait Fail instead of waiting for the rows locked by other transactions.
+	LockNoWait = "NOWAIT"
+	// LockSkipLocked Skip the rows locked by other transactions.
+	LockSkipLocked = "SKIP LOCKED"
+)
+
 func NewBuilder(connection *Connection, grammar grammar.Grammar) *Builder {
 
 	return &Builder{
 		Connection: connection,
 		grammar:    grammar,
-		Query:      &query.Query{},
+		Query:      &query.Query{Returning: "id"},
 		Bindings:   getDefaultBindings(),
 	}
 }
 
+// WithContext Set the context the query runs with.
+func (b *Builder) WithContext(ctx context.Context) *Builder {
+	b.ctx = ctx
+	return b
+}
+
 // Select the columns to be selected.
 func (b *Builder) Select(columns ...string) *Builder {
 	if len(columns) == 0 {
@@ -49,6 +76,14 @@ func (b *Builder) SelectRaw(expression string, bindings ...interface{}) *Builder
 	return b
 }
 
+// SelectSub Add a subselect expression to the query.
+func (b *Builder) SelectSub(sub *Builder, alias string) *Builder {
+	return b.SelectRaw(
+		"("+b.grammar.CompileSubSelect(sub.Query)+") as "+b.grammar.Wrap(alias, false),
+		sub.GetBindings()...,
+	)
+}
+
 func (b *Builder) AddSelect(columns ...string) *Builder {
 	for _, column := range columns {
 		b.Query.Columns = append(b.Query.Columns, column)
@@ -63,6 +98,15 @@ func (b *Builder) From(table string) *Builder {
 	return b
 }
 
+// FromSub Makes "from" fetch from a subquery.
+func (b *Builder) FromSub(sub *Builder, alias string) *Builder {
+	b.Query.From = alias
+	b.Query.FromQuery = sub.Query
+	b.Bindings["from"] = nil
+	b.AddBinding(sub.GetBindings(), "from")
+	return b
+}
+
 // Join Add a join clause to the query.
 func (b *Builder) Join(table string, first string, args ...interface{}) *Builder {
 	var (
@@ -315,6 +359,40 @@ func (b *Builder) OrWhereRaw(sql string, bindings ...interface{}) *Builder {
 	return b
 }
 
+// WhereGroup Add a nested where statement to the query, the wheres added by
+// the callback are wrapped in parentheses.
+func (b *Builder) WhereGroup(callback func(q *Builder), args ...string) *Builder {
+	boolean := "and"
+	if len(args) > 0 {
+		boolean = args[0]
+	}
+
+	nested := b.forNestedWhere()
+	callback(nested)
+
+	if len(nested.Query.Wheres) > 0 {
+		b.Query.Wheres = append(b.Query.Wheres, &query.Where{
+			Type:    "Nested",
+			Query:   nested.Query,
+			Boolean: boolean,
+		})
+
+		b.AddBinding(nested.Bindings["where"], "where")
+	}
+
+	return b
+}
+
+// OrWhereGroup Add a nested "or where" statement to the query.
+func (b *Builder) OrWhereGroup(callback func(q *Builder)) *Builder {
+	return b.WhereGroup(callback, "or")
+}
+
+// forNestedWhere Create a new query instance for nested where condition.
+func (b *Builder) forNestedWhere() *Builder {
+	return NewBuilder(b.Connection, b.grammar).From(b.Query.From)
+}
+
 // WhereIn Add a "where in" clause to the query.
 func (b *Builder) WhereIn(column string, values []interface{}, args ...interface{}) *Builder {
 	var (
@@ -360,6 +438,85 @@ func (b *Builder) OrWhereIn(column string, values []interface{}) *Builder {
 	return b.WhereIn(column, values, "OR")
 }
 
+// WhereInSub Add a "where in" clause with a subquery to the query.
+func (b *Builder) WhereInSub(column string, sub *Builder, args ...interface{}) *Builder {
+	boolean := "and"
+	not := false
+
+	if len(args) > 0 {
+		boolean, _ = args[0].(string)
+	}
+	if len(args) > 1 {
+		not, _ = args[1].(bool)
+	}
+
+	t := "InSub"
+	if not {
+		t = "NotInSub"
+	}
+	b.Query.Wheres = append(
+		b.Query.Wheres,
+		&query.Where{
+			Type:    t,
+			Column:  column,
+			Query:   sub.Query,
+			Boolean: boolean,
+		},
+	)
+	b.AddBinding(sub.GetBindings(), "where")
+
+	return b
+}
+
+// WhereNotInSub Add a "where not in" clause with a subquery to the query.
+func (b *Builder) WhereNotInSub(column string, sub *Builder) *Builder {
+	return b.WhereInSub(column, sub, "and", true)
+}
+
+// WhereExists Add an exists clause to the query.
+func (b *Builder) WhereExists(sub *Builder, args ...interface{}) *Builder {
+	boolean := "and"
+	not := false
+
+	if len(args) > 0 {
+		boolean, _ = args[0].(string)
+	}
+	if len(args) > 1 {
+		not, _ = args[1].(bool)
+	}
+
+	t := "Exists"
+	if not {
+		t = "NotExists"
+	}
+	b.Query.Wheres = append(
+		b.Query.Wheres,
+		&query.Where{
+			Type:    t,
+			Query:   sub.Query,
+			Boolean: boolean,
+		},
+	)
+	b.AddBinding(sub.GetBindings(), "where")
+
+	return b
+}
+
+// OrWhereExists Add an or exists clause to the query.
+func (b *Builder) OrWhereExists(sub *Builder) *Builder {
+	return b.WhereExists(sub, "or")
+}
+
+// WhereNotExists Add a where not exists clause to the query.
+func (b *Builder) WhereNotExists(sub *Builder) *Builder {
+	return b.WhereExists(sub, "and", true)
+}
+
+// OrWhereNotExists Add a where not exists clause to the query.
+func (b *Builder) OrWhereNotExists(sub *Builder) *Builder {
+	return b.WhereExists(sub, "or", true)
+}
+
 // WhereNotIn Add a "where not in" clause to the query.
 func (b *Builder) WhereNotIn(column string, values []interface{}, args ...interface{}) *Builder {
 	var boolean string
@@ -602,10 +759,60 @@ func (b *Builder) OrderByRaw(sql string, bindings ...interface{}) *Builder {
 		)
 	}
 
-	if len(bindings) != 0 {
+	if len(bindings) == 0 {
+		return b
+	}
+
+	// The orders of a union are compiled after the unions, and so are their bindings.
+	if len(b.Query.Unions) == 0 {
 		b.AddBinding(bindings, "order")
+	} else {
+		b.AddBinding(bindings, "unionOrder")
+	}
+
+	return b
+}
+
+// Union Add a union statement to the query, the orders, limit and offset set
+// after the union apply to the whole union.
+func (b *Builder) Union(other *Builder, args ...bool) *Builder {
+	all := false
+	if len(args) > 0 {
+		all = args[0]
 	}
 
+	b.Query.Unions = append(b.Query.Unions, &query.Union{
+		Query: other.Query,
+		All:   all,
+	})
+
+	b.AddBinding(other.GetBindings(), "union")
+
+	return b
+}
+
+// UnionAll Add a union all statement to the query.
+func (b *Builder) UnionAll(other *Builder) *Builder {
+	return b.Union(other, true)
+}
+
+// Lock Lock the selected rows in the table with a raw lock clause.
+func (b *Builder) Lock(sql string) *Builder {
+	b.Query.Lock = &query.Lock{Type: "raw", Sql: sql}
+	return b
+}
+
+// LockForUpdate Lock the selected rows in the table for updating, with the
+// LockNoWait or LockSkipLocked option.
+func (b *Builder) LockForUpdate(option ...string) *Builder {
+	b.Query.Lock = &query.Lock{Type: "update", Option: lockOption(option)}
+	return b
+}
+
+// SharedLock Share lock the selected rows in the table, with the LockNoWait
+// or LockSkipLocked option.
+func (b *Builder) SharedLock(option ...string) *Builder {
+	b.Query.Lock = &query.Lock{Type: "share", Option: lockOption(option)}
 	return b
 }
 
@@ -676,29 +883,49 @@ func (b *Builder) Aggregate(function string, columns []string, dest ...interface
 		cols = columns
 	}
 
-	nb := b.CloneWithout("columns").CloneWithoutBindings("select").setAggregate(function, cols)
+	// The query is restored once the aggregate has run, so the builder can
+	// still be used to get the records.
+	original, selects, aggregate := b.Query.Columns, b.Bindings["select"], b.Query.Aggregate
+	defer func() {
+		b.Query.Columns, b.Bindings["select"], b.Query.Aggregate = original, selects, aggregate
+	}()
 
-	original := nb.Query.Columns
-
-	if original == nil {
-		nb.Query.Columns = cols
+	// The aggregate of a union is computed on a subquery of the union, whose
+	// columns must still match the columns of the unioned queries.
+	if len(b.Query.Unions) == 0 {
+		b.CloneWithout("columns").CloneWithoutBindings("select").Query.Columns = cols
 	}
 
-	return nb.Scan(dest...)
+	return b.setAggregate(function, cols).Scan(dest...)
 }
 
 func (b *Builder) ToSql() string {
 	return b.grammar.CompileSelect(b.Query)
 }
 
+// ToRawSql Get the raw SQL representation of the query with embedded bindings,
+// for debugging purposes only: the bindings are escaped by the grammar but the
+// result should not be executed.
+func (b *Builder) ToRawSql() string {
+	return b.grammar.SubstituteBindingsIntoRawSql(b.ToSql(), b.GetBindings())
+}
+
+// Dump Print the raw SQL of the query with embedded bindings.
+func (b *Builder) Dump() *Builder {
+	fmt.Println(b.ToRawSql())
+	return b
+}
+
 // AddBinding Add a binding to the query.
 func (b *Builder) AddBinding(value interface{}, segment string) {
 	if _, ok := b.Bindings[segment]; !ok {
 		return
 	}
 	t := reflect.TypeOf(value)
-	switch t.Kind() {
-	case reflect.Slice:
+	switch {
+	case t == nil, t == reflect.TypeOf([]byte(nil)):
+		b.Bindings[segment] = append(b.Bindings[segment], value)
+	case t.Kind() == reflect.Slice:
 		s := reflect.ValueOf(value)
 		for i := 0; i < s.Len(); i++ {
 			b.Bindings[segment] = append(b.Bindings[segment], s.Index(i).Interface())
@@ -708,14 +935,9 @@ func (b *Builder) AddBinding(value interface{}, segment string) {
 	}
 }
 
+// GetBindings Get the current query value bindings in the order of the SQL clauses.
 func (b *Builder) GetBindings() []interface{} {
-	var bindings []interface{}
-	for _, val := range b.Bindings {
-		for _, v := range val {
-			bindings = append(bindings, v)
-		}
-	}
-	return bindings
+	return grammar.FlattenBindings(b.Bindings)
 }
 
 // CloneWithout Clone the query without the given properties.
@@ -745,8 +967,40 @@ func (b *Builder) CloneWithoutBindings(except ...string) *Builder {
 	return builder
 }
 
-// GetConnection Get the database connection instance.
+// OmitBlank Skip the blank fields of the structs inserted or updated by the query.
+func (b *Builder) OmitBlank() *Builder {
+	b.omitBlank = true
+	return b
+}
+
+// FillMissingWithDefault Insert the default value of the columns missing from
+// some of the inserted rows, instead of failing.
+func (b *Builder) FillMissingWithDefault() *Builder {
+	b.Query.FillMissing = "DEFAULT"
+	return b
+}
+
+// FillMissingWithNull Insert NULL in the columns missing from some of the
+// inserted rows, instead of failing.
+func (b *Builder) FillMissingWithNull() *Builder {
+	b.Query.FillMissing = "NULL"
+	return b
+}
+
+// Returning Set the column returned as the inserted id on the databases which
+// can't report it through the driver, "id" by default. The inserts return no id
+// when the column is empty.
+func (b *Builder) Returning(column string) *Builder {
+	b.Query.Returning = column
+	return b
+}
+
+// GetConnection Get the database connection instance, which runs the queries
+// with the context of the builder.
 func (b *Builder) GetConnection() *Connection {
+	if b.ctx != nil {
+		return b.Connection.WithContext(b.ctx)
+	}
 	return b.Connection
 }
 
@@ -756,7 +1010,7 @@ func (b *Builder) GetGrammar() grammar.Grammar {
 }
 
 func (b *Builder) runSelect(dest interface{}) error {
-	return b.Connection.Select(
+	return b.GetConnection().Select(
 		b.ToSql(),
 		b.GetBindings(),
 		dest,
@@ -764,7 +1018,7 @@ func (b *Builder) runSelect(dest interface{}) error {
 }
 
 func (b *Builder) runScan(dest ...interface{}) error {
-	return b.Connection.Scan(
+	return b.GetConnection().Scan(
 		b.ToSql(),
 		b.GetBindings(),
 		dest...,
@@ -788,15 +1042,276 @@ func (b *Builder) Insert(value map[string]interface{}) (int64, int64, error) {
 
 // Inserts Bulk insert records into the database.
 func (b *Builder) Inserts(values []map[string]interface{}) (int64, int64, error) {
-	sql, bindings := b.GetGrammar().CompileInsert(b.Query, values)
-	return b.Connection.Insert(sql, bindings...)
+	sql, bindings, err := b.GetGrammar().CompileInsert(b.Query, values)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	returning := b.GetGrammar().ReturnsInsertId() && b.Query.Returning != ""
+	return b.GetConnection().insert(sql, returning, bindings...)
+}
+
+// InsertUsing Insert new records into the table using a subquery, and get the
+// number of inserted rows, reported by the driver since no id is returned.
+func (b *Builder) InsertUsing(columns []string, source *Builder) (int64, error) {
+	sql := b.GetGrammar().CompileInsertUsing(b.Query, columns, source.Query)
+
+	_, affected, err := b.GetConnection().Insert(sql, source.GetBindings()...)
+	return affected, err
+}
+
+// InsertOrIgnore Insert new records into the database while ignoring errors,
+// such as the duplicated keys, and get the number of inserted rows.
+func (b *Builder) InsertOrIgnore(values []map[string]interface{}) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	sql, bindings, err := b.GetGrammar().CompileInsertOrIgnore(b.Query, values)
+	if err != nil {
+		return 0, err
+	}
+
+	_, affected, err := b.GetConnection().Insert(sql, bindings...)
+	return affected, err
+}
+
+// Upsert Insert new records or update the existing ones conflicting on the
+// uniqueBy columns, every inserted column is updated when update is empty.
+func (b *Builder) Upsert(values []map[string]interface{}, uniqueBy []string, update []string) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	if len(update) == 0 {
+		for column := range values[0] {
+			update = append(update, column)
+		}
+		sort.Strings(update)
+	}
+
+	sql, bindings, err := b.GetGrammar().CompileUpsert(b.Query, values, uniqueBy, update)
+	if err != nil {
+		return 0, err
+	}
+
+	_, affected, err := b.GetConnection().Insert(sql, bindings...)
+	return affected, err
+}
+
+// InsertStruct Insert the fields of a struct into the database, a blank primary
+// key is skipped and set to the inserted id when the struct is addressable.
+func (b *Builder) InsertStruct(value interface{}) (int64, int64, error) {
+	schema, err := NewSchema(value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	values := schema.Values(b.omitBlank)
+	primary := schema.PrimaryField
+	if primary != nil && primary.IsBlank {
+		delete(values, primary.Name)
+	}
+
+	insertId, affected, err := b.Returning(schema.PrimaryName()).Insert(values)
+	if err != nil {
+		return insertId, affected, err
+	}
+
+	if primary != nil && primary.IsBlank && primary.Value.CanSet() {
+		err = primary.SetValue(insertId)
+	}
+
+	return insertId, affected, err
+}
+
+// InsertStructs Bulk insert the fields of a slice of structs into the database,
+// the rows must have the same columns so a blank field is only skipped when it
+// is blank in every struct.
+func (b *Builder) InsertStructs(values interface{}) (int64, int64, error) {
+	rows, err := b.structRows(values)
+	if err != nil || len(rows) == 0 {
+		return 0, 0, err
+	}
+
+	return b.Inserts(rows)
+}
+
+// InsertStructsBatch Bulk insert the fields of a slice of structs in chunks,
+// see InsertStructs and InsertBatch.
+func (b *Builder) InsertStructsBatch(values interface{}, batchSize int) (BatchResult, error) {
+	rows, err := b.structRows(values)
+	if err != nil {
+		return BatchResult{}, err
+	}
+
+	return b.InsertBatch(rows, batchSize)
+}
+
+// InsertBatch Bulk insert records into the database in chunks of at most
+// batchSize rows, smaller when the rows would exceed the place-holders or the
+// rows of a statement. The chunks are inserted in a single transaction.
+func (b *Builder) InsertBatch(values []map[string]interface{}, batchSize int) (BatchResult, error) {
+	var result BatchResult
+
+	if len(values) == 0 {
+		return result, nil
+	}
+
+	columns := make(map[string]bool)
+	for _, row := range values {
+		for column := range row {
+			columns[column] = true
+		}
+	}
+
+	if len(columns) == 0 {
+		return result, errors.New("There are no columns to insert.")
+	}
+
+	size := b.GetGrammar().MaxParameters() / len(columns)
+	if rows := b.GetGrammar().MaxInsertRows(); rows > 0 && rows < size {
+		size = rows
+	}
+	if batchSize > 0 && batchSize < size {
+		size = batchSize
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	firstReported := b.GetGrammar().ReportsFirstInsertId()
+
+	err := b.GetConnection().Transaction(func(tx *Connection) error {
+		chunk := *b
+		chunk.Connection = tx
+		chunk.ctx = nil
+
+		for start := 0; start < len(values); start += size {
+			end := start + size
+			if end > len(values) {
+				end = len(values)
+			}
+
+			insertId, affected, err := chunk.Inserts(values[start:end])
+			if err != nil {
+				return err
+			}
+
+			first, last := insertId-affected+1, insertId
+			if firstReported {
+				first, last = insertId, insertId+affected-1
+			}
+			if insertId == 0 {
+				first, last = 0, 0
+			}
+
+			if start == 0 {
+				result.FirstInsertId = first
+			}
+			result.LastInsertId = last
+			result.RowsAffected += affected
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return BatchResult{}, err
+	}
+
+	return result, nil
+}
+
+// structRows Get the column values of a slice of structs, a blank field is
+// skipped when it is blank in every struct. The inserts return the primary key
+// of the structs.
+func (b *Builder) structRows(values interface{}) ([]map[string]interface{}, error) {
+	slice := reflect.Indirect(reflect.ValueOf(values))
+	if slice.Kind() != reflect.Slice {
+		return nil, errors.New("unsupported value, should be slice")
+	}
+
+	var rows []map[string]interface{}
+	blanks := make(map[string]bool)
+
+	for i := 0; i < slice.Len(); i++ {
+		schema, err := NewSchema(slice.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			b.Returning(schema.PrimaryName())
+		}
+
+		rows = append(rows, schema.Values(false))
+		for _, field := range schema.Fields {
+			blank := field.IsBlank && (b.omitBlank || field.Primary)
+			if i == 0 {
+				blanks[field.Name] = blank
+			} else {
+				blanks[field.Name] = blanks[field.Name] && blank
+			}
+		}
+	}
+
+	for column, blank := range blanks {
+		if !blank {
+			continue
+		}
+		for _, row := range rows {
+			delete(row, column)
+		}
+	}
+
+	return rows, nil
+}
+
+// UpdateStruct Update the records with the fields of a struct, restricted to
+// the given columns when there are some. The primary key is never updated, and
+// the record of the struct is updated when the query has no where clause.
+func (b *Builder) UpdateStruct(value interface{}, columns ...string) (int64, error) {
+	schema, err := NewSchema(value)
+	if err != nil {
+		return 0, err
+	}
+
+	values := schema.Values(b.omitBlank)
+	primary := schema.PrimaryField
+	if primary != nil {
+		delete(values, primary.Name)
+	}
+
+	if len(columns) > 0 {
+		only := make(map[string]interface{})
+		for _, column := range columns {
+			if v, ok := values[column]; ok {
+				only[column] = v
+			}
+		}
+		values = only
+	}
+
+	if len(values) == 0 {
+		return 0, errors.New("There are no columns to update.")
+	}
+
+	if len(b.Query.Wheres) == 0 {
+		if primary == nil || primary.IsBlank {
+			return 0, errors.New("The struct has no primary key to update.")
+		}
+		b.Where(primary.Name, primary.Value.Interface())
+	}
+
+	return b.Update(values)
 }
 
 // Update a record in the database.
 func (b *Builder) Update(value map[string]interface{}) (int64, error) {
 	sql := b.GetGrammar().CompileUpdate(b.Query, value)
 	cleanBindings := cleanBindings(b.GetGrammar().PrepareBindingsForUpdate(b.Bindings, value))
-	return b.Connection.Update(
+	return b.GetConnection().Update(
 		sql,
 		cleanBindings...,
 	)
@@ -805,10 +1320,14 @@ func (b *Builder) Update(value map[string]interface{}) (int64, error) {
 // Delete a record from the database.
 func (b *Builder) Delete(args ...interface{}) (int64, error) {
 	if len(args) > 0 {
-		b.Where(b.Query.From+".id", args[0])
+		table := b.Query.From
+		if i := strings.Index(strings.ToLower(table), " as "); i >= 0 {
+			table = strings.TrimSpace(table[i+4:])
+		}
+		b.Where(table+".id", args[0])
 	}
 
-	return b.Connection.Delete(
+	return b.GetConnection().Delete(
 		b.GetGrammar().CompileDelete(b.Query),
 		b.GetBindings()...,
 	)
@@ -825,13 +1344,18 @@ func cleanBindings(bindings []interface{}) []interface{} {
 	return result
 }
 
+// lockOption Get the lock option of the optional arguments.
+func lockOption(option []string) string {
+	if len(option) > 0 {
+		return option[0]
+	}
+	return ""
+}
+
 func getDefaultBindings() map[string][]interface{} {
-	return map[string][]interface{}{
-		"select": make([]interface{}, 0),
-		"join":   make([]interface{}, 0),
-		"where":  make([]interface{}, 0),
-		"having": make([]interface{}, 0),
-		"order":  make([]interface{}, 0),
-		"union":  make([]interface{}, 0),
+	bindings := make(map[string][]interface{}, len(grammar.BindingSegments))
+	for _, segment := range grammar.BindingSegments {
+		bindings[segment] = make([]interface{}, 0)
 	}
+	return bindings
 }
@@ -1,17 +1,31 @@
 package torm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
 	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/thinkoner/torm/grammar"
 )
 
 type Connection struct {
-	DB          *sql.DB
-	tablePrefix string
+	DB            *sql.DB
+	readDB        *sql.DB
+	tx            *sql.Tx
+	transactions  int
+	tablePrefix   string
+	queryGrammar  grammar.Grammar
+	sticky        bool
+	modified      *int32
+	ctx           context.Context
+	logger        Logger
+	slowThreshold time.Duration
+	events        *dispatcher
+	queryLog      *queryLog
 }
 
 // Table Begin a fluent query against a database table.
@@ -19,6 +33,31 @@ func (c *Connection) Table(table string) *Builder {
 	return c.Query().From(table)
 }
 
+// Session Get a copy of the connection which records its own writes, when the
+// connection is sticky the selects run after a write of the session are sent
+// to the write database. A session is typically used for a single request.
+func (c *Connection) Session() *Connection {
+	conn := *c
+	conn.modified = new(int32)
+	return &conn
+}
+
+// WithContext Get a copy of the connection which runs its queries with the
+// context, so they are canceled when the context is done.
+func (c *Connection) WithContext(ctx context.Context) *Connection {
+	conn := *c
+	conn.ctx = ctx
+	return &conn
+}
+
+// Context Get the context the queries of the connection run with.
+func (c *Connection) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // Query Get a new query builder instance.
 func (c *Connection) Query() *Builder {
 	return NewBuilder(c, c.GetQueryGrammar())
@@ -31,34 +70,40 @@ func (c *Connection) SelectOne(query string, bindings []interface{}, dest interf
 
 // Select Run a select statement against the database.
 func (c *Connection) Select(query string, bindings []interface{}, dest interface{}) error {
+	_, err := c.run(query, bindings, func() (int64, error) {
+		return c.runSelect(query, bindings, dest)
+	})
+	return err
+}
 
-	log.Println(query)
-
+// runSelect Run a select statement and get the number of fetched rows.
+func (c *Connection) runSelect(query string, bindings []interface{}, dest interface{}) (int64, error) {
 	var err error
+	var fetched int64
 
-	stmt, err := c.DB.Prepare(query)
+	stmt, err := c.prepareRead(query)
 
 	if err != nil {
-		return err
+		return fetched, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(bindings...)
+	rows, err := stmt.QueryContext(c.Context(), bindings...)
 	if err != nil {
-		return err
+		return fetched, err
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return err
+		return fetched, err
 	}
 
 	results := reflect.Indirect(reflect.ValueOf(dest))
 
 	kind := results.Kind()
 	if kind != reflect.Slice && kind != reflect.Struct {
-		return errors.New("unsupported destination, should be slice or struct")
+		return fetched, errors.New("unsupported destination, should be slice or struct")
 	}
 
 	var isPtr bool
@@ -90,8 +135,9 @@ func (c *Connection) Select(query string, bindings []interface{}, dest interface
 	for rows.Next() {
 		err := c.scan(rows, columns, fields)
 		if err != nil {
-			return err
+			return fetched, err
 		}
+		fetched++
 		if kind == reflect.Slice {
 			if isPtr {
 				resultValue = resultValue.Addr()
@@ -102,96 +148,436 @@ func (c *Connection) Select(query string, bindings []interface{}, dest interface
 		}
 	}
 
-	return nil
+	return fetched, rows.Err()
 }
 
+// Scan Run a select statement and scan the columns of the first row into the dest.
 func (c *Connection) Scan(query string, bindings []interface{}, dest ...interface{}) error {
-	log.Println(query)
+	_, err := c.run(query, bindings, func() (int64, error) {
+		return c.runScan(query, bindings, dest...)
+	})
+	return err
+}
 
+// runScan Run a select statement, scan the first row and get the number of fetched rows.
+func (c *Connection) runScan(query string, bindings []interface{}, dest ...interface{}) (int64, error) {
 	var err error
 
-	stmt, err := c.DB.Prepare(query)
+	stmt, err := c.prepareRead(query)
 
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(bindings...)
+	rows, err := stmt.QueryContext(c.Context(), bindings...)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer rows.Close()
 
-	rows.Next()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, rows.Scan(dest...)
+	}
 
-	return rows.Scan(dest...)
+	return 1, rows.Scan(dest...)
 }
 
 // Insert Run an insert statement against the database.
+//
+// When the grammar returns the inserted id as a result set, as PostgreSQL does,
+// the driver can't report the id of a raw insert and it is 0, the builder
+// inserts read it from their returning clause instead.
 func (c *Connection) Insert(query string, args ...interface{}) (int64, int64, error) {
-	return c.affectingStatement(query, args...)
+	return c.insert(query, false, args...)
+}
+
+// insert Run an insert statement against the database, the inserted id is read
+// from the rows returned by the statement when returning is true.
+func (c *Connection) insert(query string, returning bool, args ...interface{}) (int64, int64, error) {
+	var insertId int64
+
+	affected, err := c.run(query, args, func() (int64, error) {
+		var affected int64
+		var err error
+
+		if returning {
+			insertId, affected, err = c.returningStatement(query, args...)
+		} else {
+			insertId, affected, err = c.affectingStatement(query, args...)
+		}
+		return affected, err
+	})
+
+	return insertId, affected, err
 }
 
 // Update Run an update statement against the database.
 func (c *Connection) Update(query string, args ...interface{}) (int64, error) {
-	_, affected, err := c.affectingStatement(query, args...)
-	return affected, err
+	return c.run(query, args, func() (int64, error) {
+		_, affected, err := c.affectingStatement(query, args...)
+		return affected, err
+	})
 }
 
 // Delete Run a delete statement against the database.
 func (c *Connection) Delete(query string, args ...interface{}) (int64, error) {
-	_, affected, err := c.affectingStatement(query, args...)
-	return affected, err
+	return c.run(query, args, func() (int64, error) {
+		_, affected, err := c.affectingStatement(query, args...)
+		return affected, err
+	})
 }
 
 // Statement Execute an SQL statement and return the boolean result.
 func (c *Connection) Statement(query string, args ...interface{}) error {
+	_, err := c.run(query, args, func() (int64, error) {
+		stmt, err := c.prepare(query)
+		if err != nil {
+			return 0, err
+		}
+		defer stmt.Close()
+
+		result, err := stmt.ExecContext(c.Context(), args...)
+		if err != nil {
+			return 0, err
+		}
+		c.recordModification()
+
+		affected, _ := result.RowsAffected()
+		return affected, nil
+	})
+
+	return err
+}
+
+// Begin Start a new database transaction, the returned connection runs
+// every query through the transaction. Calling Begin on a connection that
+// is already in a transaction creates a savepoint instead.
+func (c *Connection) Begin() (*Connection, error) {
+	if c.transactions > 0 {
+		err := c.Statement(c.GetQueryGrammar().CompileSavepoint(c.savepoint(c.transactions + 1)))
+		if err != nil {
+			return nil, err
+		}
+		c.transactions++
+		c.fireTransactionEvent(TransactionBeginning, c.transactions)
+
+		return c, nil
+	}
+
+	tx, err := c.DB.BeginTx(c.Context(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := *c
+	conn.tx = tx
+	conn.transactions = 1
+	conn.fireTransactionEvent(TransactionBeginning, 1)
+
+	return &conn, nil
+}
+
+// Commit Commit the active database transaction, or release the current
+// savepoint of a nested transaction.
+func (c *Connection) Commit() error {
+	var err error
+
+	switch {
+	case c.transactions == 0:
+		return errors.New("There is no active transaction.")
+	case c.transactions == 1:
+		err = c.tx.Commit()
+		c.tx = nil
+	default:
+		if sql := c.GetQueryGrammar().CompileSavepointRelease(c.savepoint(c.transactions)); sql != "" {
+			err = c.Statement(sql)
+		}
+	}
+	if err == nil {
+		c.fireTransactionEvent(TransactionCommitted, c.transactions)
+	}
+	c.transactions--
+
+	return err
+}
+
+// Rollback Rollback the active database transaction, or roll back to the
+// current savepoint of a nested transaction.
+func (c *Connection) Rollback() error {
 	var err error
 
-	stmt, err := c.DB.Prepare(query)
+	switch {
+	case c.transactions == 0:
+		return errors.New("There is no active transaction.")
+	case c.transactions == 1:
+		err = c.tx.Rollback()
+		c.tx = nil
+	default:
+		err = c.Statement(c.GetQueryGrammar().CompileSavepointRollBack(c.savepoint(c.transactions)))
+	}
+	if err == nil {
+		c.fireTransactionEvent(TransactionRolledBack, c.transactions)
+	}
+	c.transactions--
+
+	return err
+}
+
+// TransactionLevel Get the number of active transactions.
+func (c *Connection) TransactionLevel() int {
+	return c.transactions
+}
+
+// Transaction Execute a Closure within a transaction, the transaction is
+// rolled back when the Closure returns an error or panics. Nested calls
+// are wrapped in savepoints.
+func (c *Connection) Transaction(callback func(tx *Connection) error) (err error) {
+	tx, err := c.Begin()
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(args...)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
 
-	return err
+	if err = callback(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Listen Register a listener called after each SQL statement executed by the
+// connection, or by the sessions and transactions started from it.
+func (c *Connection) Listen(listener func(QueryEvent)) {
+	c.dispatcher().listen(listener)
+}
+
+// ListenTransaction Register a listener called when a transaction of the
+// connection begins, is committed or is rolled back.
+func (c *Connection) ListenTransaction(listener func(TransactionEvent)) {
+	c.dispatcher().listenTransaction(listener)
+}
+
+// SetLogger Set the logger of the executed queries.
+func (c *Connection) SetLogger(logger Logger) {
+	c.logger = logger
+}
+
+// SetSlowThreshold Set the duration from which the queries are logged as slow.
+func (c *Connection) SetSlowThreshold(threshold time.Duration) {
+	c.slowThreshold = threshold
+}
+
+// GetTablePrefix Get the table prefix for the connection.
+func (c *Connection) GetTablePrefix() string {
+	return c.tablePrefix
 }
 
+// GetQueryGrammar Get the query grammar used by the connection.
 func (c *Connection) GetQueryGrammar() grammar.Grammar {
-	return &grammar.MySqlGrammar{}
+	if c.queryGrammar == nil {
+		return grammar.NewMySqlGrammar(c.tablePrefix)
+	}
+	return c.queryGrammar
+}
+
+// SetQueryGrammar Set the query grammar used by the connection.
+func (c *Connection) SetQueryGrammar(queryGrammar grammar.Grammar) {
+	c.queryGrammar = queryGrammar
 }
 
 // AffectingStatement Run an SQL statement and get the number of rows affected.
 func (c *Connection) affectingStatement(query string, args ...interface{}) (int64, int64, error) {
 	var err error
 
-	log.Println(query)
-
-	stmt, err := c.DB.Prepare(query)
+	stmt, err := c.prepare(query)
 
 	if err != nil {
 		return 0, 0, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.Exec(args...)
+	result, err := stmt.ExecContext(c.Context(), args...)
 
 	if err != nil {
 		return 0, 0, err
 	}
+	c.recordModification()
 
 	affected, err := result.RowsAffected()
 	if err != nil {
 		return 0, affected, err
 	}
 
+	if c.GetQueryGrammar().ReturnsInsertId() {
+		return 0, affected, nil
+	}
+
 	insertId, err := result.LastInsertId()
 	return insertId, affected, err
 }
 
+// Close Close the database and the read database of the connection.
+func (c *Connection) Close() error {
+	var err error
+	if c.readDB != nil {
+		err = c.readDB.Close()
+	}
+	if e := c.DB.Close(); e != nil {
+		err = e
+	}
+	return err
+}
+
+// Stats Get the statistics of the write database pool.
+func (c *Connection) Stats() sql.DBStats {
+	return c.DB.Stats()
+}
+
+// ReadStats Get the statistics of the read database pool, which is the write
+// database pool when no read replica is configured.
+func (c *Connection) ReadStats() sql.DBStats {
+	if c.readDB != nil {
+		return c.readDB.Stats()
+	}
+	return c.DB.Stats()
+}
+
+// savepoint Get the savepoint name of the given transaction level.
+func (c *Connection) savepoint(level int) string {
+	return fmt.Sprintf("trans%d", level)
+}
+
+// run Run an SQL statement and log its execution, the callback returns the
+// number of rows affected or fetched by the statement.
+func (c *Connection) run(query string, bindings []interface{}, callback func() (int64, error)) (int64, error) {
+	start := time.Now()
+
+	rows, err := callback()
+
+	executed := ExecutedQuery{
+		SQL:          query,
+		Bindings:     bindings,
+		Duration:     time.Since(start),
+		RowsAffected: rows,
+		Err:          err,
+	}
+
+	c.logQuery(executed)
+
+	if c.queryLog != nil {
+		c.queryLog.record(executed)
+	}
+
+	if c.events != nil {
+		c.events.dispatchQuery(QueryEvent{ExecutedQuery: executed, Connection: c})
+	}
+
+	return rows, err
+}
+
+// logQuery Log a query to the logger of the connection, failed queries are
+// logged as errors and the queries slower than the threshold as warnings.
+func (c *Connection) logQuery(query ExecutedQuery) {
+	if c.logger == nil {
+		return
+	}
+
+	level := LogDebug
+	if query.Err != nil {
+		level = LogError
+	} else if c.slowThreshold > 0 && query.Duration >= c.slowThreshold {
+		level = LogWarn
+	}
+
+	c.logger.LogQuery(c.Context(), level, query)
+}
+
+// fireTransactionEvent Dispatch a transaction event to the listeners.
+func (c *Connection) fireTransactionEvent(eventType TransactionEventType, level int) {
+	if c.events != nil {
+		c.events.dispatchTransaction(TransactionEvent{Type: eventType, Level: level, Connection: c})
+	}
+}
+
+// dispatcher Get the event dispatcher of the connection.
+func (c *Connection) dispatcher() *dispatcher {
+	if c.events == nil {
+		c.events = &dispatcher{}
+	}
+	return c.events
+}
+
+// prepare Create a prepared statement on the active transaction or the database.
+func (c *Connection) prepare(query string) (*sql.Stmt, error) {
+	if c.tx != nil {
+		return c.tx.PrepareContext(c.Context(), query)
+	}
+	return c.DB.PrepareContext(c.Context(), query)
+}
+
+// returningStatement Run an SQL statement returning the inserted ids and get the
+// last inserted id and the number of rows affected.
+func (c *Connection) returningStatement(query string, args ...interface{}) (int64, int64, error) {
+	var err error
+
+	stmt, err := c.prepare(query)
+
+	if err != nil {
+		return 0, 0, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(c.Context(), args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	c.recordModification()
+
+	var insertId, affected int64
+	for rows.Next() {
+		if err = rows.Scan(&insertId); err != nil {
+			return 0, affected, err
+		}
+		affected++
+	}
+
+	return insertId, affected, rows.Err()
+}
+
+// prepareRead Create a prepared statement for a select statement, which runs
+// on the read database unless a transaction is active or the sticky session
+// has written to the database.
+func (c *Connection) prepareRead(query string) (*sql.Stmt, error) {
+	if c.tx == nil && c.readDB != nil && !(c.sticky && c.recordsModified()) {
+		return c.readDB.PrepareContext(c.Context(), query)
+	}
+	return c.prepare(query)
+}
+
+// recordModification Record that the session has written to the database.
+func (c *Connection) recordModification() {
+	if c.modified != nil {
+		atomic.StoreInt32(c.modified, 1)
+	}
+}
+
+// recordsModified Determine if the session has written to the database.
+func (c *Connection) recordsModified() bool {
+	return c.modified != nil && atomic.LoadInt32(c.modified) == 1
+}
+
 func (c *Connection) scan(rows *sql.Rows, columns []string, fields []*Field) error {
 	count := len(columns)
 	resets := make(map[int]*Field)
@@ -1,6 +1,8 @@
 package torm
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -71,6 +73,26 @@ func (u *User) TableName() string {
 	return "users"
 }
 
+// openSQLite Open an in-memory SQLite database named after the test, closed
+// when the test ends, and run the statements creating its schema.
+func openSQLite(t *testing.T, schema ...string) *Connection {
+	t.Helper()
+
+	conn, err := Open(Config{Driver: "sqlite3", Dsn: "file:" + t.Name() + "?mode=memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, statement := range schema {
+		if err = conn.Statement(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return conn
+}
+
 func TestConnection_SelectOne(t *testing.T) {
 	var user User
 	_, _, err := DB.Insert("insert into users (name, gender) values (?, ?)", "Andrew", "M")
@@ -148,3 +170,256 @@ func TestConnection_Delete(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestConnection_Transaction(t *testing.T) {
+	err := DB.Transaction(func(tx *Connection) error {
+		_, _, err := tx.Table("users").Insert(map[string]interface{}{
+			"name":   "Transaction",
+			"gender": "M",
+		})
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var count int64
+	err = DB.Table("users").Where("name", "Transaction").Count(&count)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 1 {
+		t.Error("Expect: the committed user should be found")
+	}
+}
+
+func TestConnection_TransactionRollback(t *testing.T) {
+	err := DB.Transaction(func(tx *Connection) error {
+		_, _, err := tx.Table("users").Insert(map[string]interface{}{
+			"name":   "Rollback",
+			"gender": "F",
+		})
+		if err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil || err.Error() != "rollback" {
+		t.Error("Expect: the callback's error should be returned")
+	}
+
+	var count int64
+	err = DB.Table("users").Where("name", "Rollback").Count(&count)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if count != 0 {
+		t.Error("Expect: the rolled back user should not be found")
+	}
+}
+
+func TestConnection_BeginCommit(t *testing.T) {
+	tx, err := DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Create(&User{Name: "Begin", Gender: "M", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	if err != nil {
+		tx.Rollback()
+		t.Fatal(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Error(err)
+	}
+
+	if err = tx.Commit(); err == nil {
+		t.Error("Expect: committing twice should fail")
+	}
+}
+
+func TestConnection_NestedTransaction(t *testing.T) {
+	err := DB.Transaction(func(tx *Connection) error {
+		_, _, err := tx.Table("users").Insert(map[string]interface{}{"name": "Outer", "gender": "M"})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Transaction(func(nested *Connection) error {
+			if nested.TransactionLevel() != 2 {
+				t.Error("Expect: the nested transaction level should be 2")
+			}
+			_, _, err := nested.Table("users").Insert(map[string]interface{}{"name": "Inner", "gender": "F"})
+			if err != nil {
+				return err
+			}
+			return errors.New("rollback inner")
+		})
+		if err == nil {
+			t.Error("Expect: the nested transaction should return its error")
+		}
+
+		if tx.TransactionLevel() != 1 {
+			t.Error("Expect: the transaction level should be back to 1")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var count int64
+	DB.Table("users").Where("name", "Outer").Count(&count)
+	if count != 1 {
+		t.Error("Expect: the outer transaction should be committed")
+	}
+
+	DB.Table("users").Where("name", "Inner").Count(&count)
+	if count != 0 {
+		t.Error("Expect: the inner transaction should be rolled back")
+	}
+}
+
+func TestConnection_WithContext(t *testing.T) {
+	conn := openSQLite(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var count int64
+	err := conn.WithContext(ctx).Scan(
+		"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c",
+		nil,
+		&count,
+	)
+	if err == nil {
+		t.Fatal("Expect: the long running query should be canceled")
+	}
+
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Error("Expect: the query should be canceled by the context deadline")
+	}
+}
+
+type recordingLogger struct {
+	levels  []LogLevel
+	queries []ExecutedQuery
+}
+
+func (l *recordingLogger) LogQuery(ctx context.Context, level LogLevel, query ExecutedQuery) {
+	l.levels = append(l.levels, level)
+	l.queries = append(l.queries, query)
+}
+
+func TestConnection_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	conn := openSQLite(t)
+	conn.SetLogger(logger)
+
+	conn.Statement("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+	conn.Insert("INSERT INTO users (name) VALUES (?), (?)", "a", "b")
+	conn.Statement("SELECT * FROM missing")
+
+	if len(logger.queries) != 3 {
+		t.Fatalf("Expected 3 logged queries but instead got %d !", len(logger.queries))
+	}
+
+	insert := logger.queries[1]
+	if insert.RowsAffected != 2 || len(insert.Bindings) != 2 || logger.levels[1] != LogDebug {
+		t.Errorf("Expected the insert to be logged with its bindings and rows but instead got %+v !", insert)
+	}
+
+	if logger.queries[2].Err == nil || logger.levels[2] != LogError {
+		t.Error("Expect: the failed query should be logged as an error")
+	}
+
+	conn.SetSlowThreshold(time.Nanosecond)
+	var count int64
+	conn.Scan("SELECT count(*) FROM users", nil, &count)
+
+	if logger.levels[3] != LogWarn {
+		t.Error("Expect: the slow query should be logged as a warning")
+	}
+}
+
+func TestConnection_Listen(t *testing.T) {
+	conn := openSQLite(t)
+
+	var queries []string
+	var transactions []TransactionEvent
+
+	conn.Listen(func(evt QueryEvent) {
+		queries = append(queries, evt.SQL)
+	})
+	conn.ListenTransaction(func(evt TransactionEvent) {
+		transactions = append(transactions, evt)
+	})
+
+	conn.Statement("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	conn.Transaction(func(tx *Connection) error {
+		tx.Insert("INSERT INTO users (name) VALUES (?)", "a")
+
+		tx.Transaction(func(nested *Connection) error {
+			return errors.New("rollback")
+		})
+		return nil
+	})
+
+	if len(queries) != 4 {
+		t.Errorf("Expected 4 dispatched queries but instead got %d !", len(queries))
+	}
+
+	expected := []TransactionEvent{
+		{Type: TransactionBeginning, Level: 1},
+		{Type: TransactionBeginning, Level: 2},
+		{Type: TransactionRolledBack, Level: 2},
+		{Type: TransactionCommitted, Level: 1},
+	}
+	if len(transactions) != len(expected) {
+		t.Fatalf("Expected %d transaction events but instead got %d !", len(expected), len(transactions))
+	}
+	for i, evt := range transactions {
+		if evt.Type != expected[i].Type || evt.Level != expected[i].Level {
+			t.Errorf("Expected event %d to be %s at level %d but instead got %s at level %d !",
+				i, expected[i].Type, expected[i].Level, evt.Type, evt.Level)
+		}
+	}
+}
+
+func TestConnection_QueryLog(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	conn.EnableQueryLog()
+	conn.Insert("INSERT INTO users (name) VALUES (?)", "a")
+	conn.Session().Insert("INSERT INTO users (name) VALUES (?)", "b")
+
+	log := conn.GetQueryLog()
+	if len(log) != 2 {
+		t.Fatalf("Expected 2 logged queries but instead got %d !", len(log))
+	}
+	if log[1].SQL != "INSERT INTO users (name) VALUES (?)" || log[1].Bindings[0] != "b" {
+		t.Errorf("Expected the session query to be logged but instead got %+v !", log[1])
+	}
+
+	var name string
+	conn.Scan("SELECT name FROM users WHERE id = ?", []interface{}{1}, &name)
+	conn.Scan("SELECT name FROM users WHERE id = ?", []interface{}{3}, &name)
+
+	log = conn.GetQueryLog()
+	if log[2].RowsAffected != 1 || log[3].RowsAffected != 0 {
+		t.Errorf("Expected the scans to fetch 1 and 0 rows but instead got %d and %d !", log[2].RowsAffected, log[3].RowsAffected)
+	}
+
+	conn.FlushQueryLog()
+	conn.DisableQueryLog()
+	conn.Statement("DELETE FROM users")
+
+	if len(conn.GetQueryLog()) != 0 {
+		t.Error("Expect: the query log should be empty")
+	}
+}
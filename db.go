@@ -2,16 +2,97 @@ package torm
 
 import (
 	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/thinkoner/torm/grammar"
 )
 
 func Open(config Config) (*Connection, error) {
-	db, err := sql.Open(config.Driver, config.Dsn)
+	dsn := config.Dsn
+	if len(config.Write) > 0 {
+		dsn = config.Write[rand.Intn(len(config.Write))]
+	}
+
+	db, err := openDB(config, dsn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Connection{
+		DB:          db,
+		tablePrefix: config.Prefix,
+		sticky:      config.Sticky,
+
+		logger:        config.Logger,
+		slowThreshold: config.SlowThreshold,
+		events:        &dispatcher{},
+		queryLog:      &queryLog{},
+	}
+
+	// The select statements are sent to one of the read replicas when they
+	// are configured, the other statements always use the write database.
+	if len(config.Read) > 0 {
+		conn.readDB, err = openDB(config, config.Read[rand.Intn(len(config.Read))])
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	// The grammar is looked up by the driver name, unless the configuration
+	// explicitly names a registered grammar for a compatible database. The
+	// drivers without a grammar of their own use the MySQL grammar.
+	var queryGrammar grammar.Grammar
+	if config.Grammar != "" {
+		queryGrammar, err = grammar.New(config.Grammar, conn.tablePrefix)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	} else if queryGrammar, err = grammar.New(config.Driver, conn.tablePrefix); err != nil {
+		queryGrammar = grammar.NewMySqlGrammar(conn.tablePrefix)
+	}
+	conn.SetQueryGrammar(queryGrammar)
+
+	return conn, nil
+}
 
+// openDB Open a database pool configured by the config.
+func openDB(config Config, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(config.Driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connection{
-		DB: db,
-	}, nil
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	if config.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	if config.Ping {
+		if err = ping(db, config.PingRetries, config.PingBackoff); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// ping Ping the database, retrying with an exponential backoff when it fails.
+func ping(db *sql.DB, retries int, backoff time.Duration) error {
+	err := db.Ping()
+
+	for i := 0; err != nil && i < retries; i++ {
+		time.Sleep(backoff)
+		backoff *= 2
+
+		err = db.Ping()
+	}
+
+	return err
 }
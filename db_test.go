@@ -0,0 +1,83 @@
+package torm
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/thinkoner/torm/grammar"
+)
+
+func TestOpen_Pool(t *testing.T) {
+	conn, err := Open(Config{
+		Driver:          "sqlite3",
+		Dsn:             "file:pool?mode=memory&cache=shared",
+		MaxOpenConns:    3,
+		MaxIdleConns:    2,
+		ConnMaxLifetime: time.Minute,
+		Ping:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stats := conn.Stats()
+	if stats.MaxOpenConnections != 3 {
+		t.Errorf("Expect: the max open connections should be 3, got %d", stats.MaxOpenConnections)
+	}
+	if stats.OpenConnections != 1 {
+		t.Errorf("Expect: the ping should open a connection, got %d", stats.OpenConnections)
+	}
+}
+
+func TestOpen_PingRetry(t *testing.T) {
+	start := time.Now()
+
+	_, err := Open(Config{
+		Driver:      "mysql",
+		Dsn:         "root:@tcp(127.0.0.1:1)/torm_test",
+		Ping:        true,
+		PingRetries: 2,
+		PingBackoff: 10 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatal("Expect: opening an unreachable database should fail")
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expect: the ping should be retried with a backoff, took %s", elapsed)
+	}
+}
+
+func init() {
+	// A driver without a grammar of its own, registered once for the package.
+	sql.Register("sqlite3_custom", &sqlite3.SQLiteDriver{})
+}
+
+func TestOpen_Grammar(t *testing.T) {
+	conn, err := Open(Config{Driver: "sqlite3_custom", Dsn: "file:custom?mode=memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, ok := conn.GetQueryGrammar().(*grammar.MySqlGrammar); !ok {
+		t.Errorf("Expected the grammar of an unknown driver to be MySQL but instead got %T !", conn.GetQueryGrammar())
+	}
+
+	conn, err = Open(Config{Driver: "sqlite3_custom", Dsn: "file:custom?mode=memory", Grammar: "sqlite3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, ok := conn.GetQueryGrammar().(*grammar.SQLiteGrammar); !ok {
+		t.Errorf("Expected the configured grammar to be SQLite but instead got %T !", conn.GetQueryGrammar())
+	}
+
+	if _, err = Open(Config{Driver: "sqlite3_custom", Dsn: "file:custom?mode=memory", Grammar: "oracle"}); err == nil {
+		t.Error("Expected an unregistered grammar to fail")
+	}
+}
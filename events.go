@@ -0,0 +1,85 @@
+package torm
+
+import "sync"
+
+// QueryEvent Dispatched after a connection executed an SQL statement.
+type QueryEvent struct {
+	ExecutedQuery
+	Connection *Connection
+}
+
+// TransactionEventType The kind of a transaction event.
+type TransactionEventType int
+
+const (
+	TransactionBeginning TransactionEventType = iota
+	TransactionCommitted
+	TransactionRolledBack
+)
+
+// String Get the name of the transaction event type.
+func (t TransactionEventType) String() string {
+	switch t {
+	case TransactionBeginning:
+		return "beginning"
+	case TransactionCommitted:
+		return "committed"
+	case TransactionRolledBack:
+		return "rolled back"
+	}
+	return "unknown"
+}
+
+// TransactionEvent Dispatched after a transaction, or the savepoint of a
+// nested transaction, has begun, been committed or been rolled back.
+type TransactionEvent struct {
+	Type TransactionEventType
+	// Level The transaction level the event applies to, 1 for the outermost
+	// transaction and above for the savepoints.
+	Level      int
+	Connection *Connection
+}
+
+// dispatcher Holds the listeners of a connection, it is shared by the copies
+// of the connection so the listeners also receive their events.
+type dispatcher struct {
+	mu                   sync.RWMutex
+	queryListeners       []func(QueryEvent)
+	transactionListeners []func(TransactionEvent)
+}
+
+// listen Register a query listener.
+func (d *dispatcher) listen(listener func(QueryEvent)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queryListeners = append(d.queryListeners, listener)
+}
+
+// listenTransaction Register a transaction listener.
+func (d *dispatcher) listenTransaction(listener func(TransactionEvent)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.transactionListeners = append(d.transactionListeners, listener)
+}
+
+// dispatchQuery Call the query listeners with the event.
+func (d *dispatcher) dispatchQuery(event QueryEvent) {
+	d.mu.RLock()
+	listeners := d.queryListeners
+	d.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// dispatchTransaction Call the transaction listeners with the event.
+func (d *dispatcher) dispatchTransaction(event TransactionEvent) {
+	d.mu.RLock()
+	listeners := d.transactionListeners
+	d.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
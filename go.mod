@@ -1,5 +1,7 @@
 module github.com/thinkoner/torm
 
+go 1.21
+
 require (
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/mattn/go-sqlite3 v1.11.0
@@ -1,8 +1,737 @@
 package grammar
 
-import "sort"
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thinkoner/torm/query"
+)
+
+// identifierRegexp Matches the plain, optionally qualified, column and table names.
+var identifierRegexp = regexp.MustCompile(`^[\w$]+(\.[\w$]+)*(\.\*)?$|^\*$`)
+
+var selectComponents = []string{
+	"aggregate",
+	"columns",
+	"from",
+	"joins",
+	"wheres",
+	"groups",
+	"havings",
+	"orders",
+	"limit",
+	"offset",
+	"lock",
+}
+
+// BindingSegments The binding segments of a query, in the order their clauses
+// appear in the compiled SQL.
+var BindingSegments = []string{
+	"select",
+	"from",
+	"join",
+	"where",
+	"having",
+	"order",
+	"union",
+	"unionOrder",
+}
+
+// FlattenBindings Get the bindings of the segments in the SQL clause order,
+// restricted to the given segments when there are some.
+func FlattenBindings(bindings map[string][]interface{}, segments ...string) []interface{} {
+	var results []interface{}
+
+	for _, segment := range BindingSegments {
+		if len(segments) > 0 && !containsString(segments, segment) {
+			continue
+		}
+		results = append(results, bindings[segment]...)
+	}
+	return results
+}
+
+// containsString Determine if the values contain the string.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dialect The pieces of SQL syntax that differ between the database grammars.
+type dialect interface {
+	// wrapValue Wrap a single string in keyword identifiers.
+	wrapValue(value string) string
+
+	// compileColumns Compile the "select *" portion of the query.
+	compileColumns(query *query.Query, columns []string) string
+
+	// compileLimit Compile the "limit" portions of the query.
+	compileLimit(query *query.Query, limit uint64) string
+
+	// compileOffset Compile the "offset" portions of the query.
+	compileOffset(query *query.Query, offset uint64) string
+
+	// compileFrom Compile the "from" portion of the query.
+	compileFrom(query *query.Query, table string) string
+
+	// compileLock Compile the lock into SQL.
+	compileLock(query *query.Query, lock *query.Lock) string
+
+	// wrapUnion Wrap a union subquery in parentheses.
+	wrapUnion(sql string) string
+
+	// escapeString Quote a string literal for the raw SQL.
+	escapeString(value string) string
+
+	// escapeBinary Quote a binary literal for the raw SQL.
+	escapeBinary(value []byte) string
+
+	// escapeBool Quote a boolean literal for the raw SQL.
+	escapeBool(value bool) string
+}
 
 type BaseGrammar struct {
+	tablePrefix string
+	dialect     dialect
+}
+
+// self Get the grammar which compiles the dialect specific pieces.
+func (g *BaseGrammar) self() dialect {
+	if g.dialect != nil {
+		return g.dialect
+	}
+	return g
+}
+
+// CompileSelect Compile a select query into SQL.
+func (g *BaseGrammar) CompileSelect(query *query.Query) string {
+	// If the query does not have any columns set, we'll set the columns to the
+	// * character to just get all of the columns from the database. Then we
+	// can build the query and concatenate all the pieces together as one.
+	if len(query.Unions) > 0 && query.Aggregate != nil {
+		return g.compileUnionAggregate(query)
+	}
+
+	original := query.Columns
+
+	if query.Columns == nil {
+		query.Columns = []string{"*"}
+	}
+
+	// To compile the query, we'll spin through each component of the query and
+	// see if that component exists. If it does we'll just call the compiler
+	// function for the component which is responsible for making the SQL.
+	sql := strings.TrimSpace(
+		g.concatenate(
+			g.compileComponents(query),
+		),
+	)
+
+	if len(query.Unions) > 0 {
+		sql = g.self().wrapUnion(sql) + " " + g.compileUnions(query)
+	}
+
+	query.Columns = original
+	return sql
+}
+
+// CompileSubSelect Compile a select query into SQL embedded in another query,
+// the place-holders are numbered when the outer query is compiled.
+func (g *BaseGrammar) CompileSubSelect(query *query.Query) string {
+	return g.CompileSelect(query)
+}
+
+// compileUnions Compile the "union" queries attached to the main query.
+func (g *BaseGrammar) compileUnions(query *query.Query) string {
+	var sql []string
+
+	for _, union := range query.Unions {
+		sql = append(sql, g.compileUnion(union))
+	}
+
+	// The union orders, limit and offset are compiled as the ones of a single
+	// query, so the dialects paginate the unions the way they paginate queries.
+	paging := unionPaging(query)
+
+	if len(paging.Orders) > 0 {
+		sql = append(sql, g.compileOrders(paging, paging.Orders))
+	}
+
+	if paging.Limit > 0 {
+		sql = append(sql, g.self().compileLimit(paging, paging.Limit))
+	}
+
+	if paging.Limit > 0 || paging.Offset > 0 {
+		sql = append(sql, g.self().compileOffset(paging, paging.Offset))
+	}
+
+	return g.concatenate(sql)
+}
+
+// compileUnion Compile a single union statement.
+func (g *BaseGrammar) compileUnion(union *query.Union) string {
+	conjunction := "union "
+	if union.All {
+		conjunction = "union all "
+	}
+
+	return conjunction + g.self().wrapUnion(g.CompileSelect(union.Query))
+}
+
+// wrapUnion Wrap a union subquery in parentheses.
+func (g *BaseGrammar) wrapUnion(sql string) string {
+	return "(" + sql + ")"
+}
+
+// compileUnionAggregate Compile a union aggregate query into SQL.
+func (g *BaseGrammar) compileUnionAggregate(query *query.Query) string {
+	aggregate := query.Aggregate
+
+	query.Aggregate = nil
+	sql := g.CompileSelect(query)
+	query.Aggregate = aggregate
+
+	return g.compileAggregate(query, aggregate) + " FROM (" + sql + ") as " + g.WrapTable("temp_table")
+}
+
+// WrapTable Wrap a table in keyword identifiers, prefixing the table and its alias.
+func (g *BaseGrammar) WrapTable(table string) string {
+	return g.Wrap(g.tablePrefix+table, true)
+}
+
+// Wrap Wrap a value in keyword identifiers, values which are not plain column
+// or table names, such as "count(name) as ct", are returned untouched.
+func (g *BaseGrammar) Wrap(value string, prefixAlias bool) string {
+	if i := strings.Index(strings.ToLower(value), " as "); i >= 0 {
+		return g.wrapAliasedValue(value[:i], value[i+4:], prefixAlias)
+	}
+
+	if !identifierRegexp.MatchString(value) {
+		return value
+	}
+
+	return g.wrapSegments(strings.Split(value, "."))
+}
+
+// wrapAliasedValue Wrap a value that has an alias.
+func (g *BaseGrammar) wrapAliasedValue(value string, alias string, prefixAlias bool) string {
+	value = strings.TrimSpace(value)
+	alias = strings.TrimSpace(alias)
+
+	if !identifierRegexp.MatchString(value) || !identifierRegexp.MatchString(alias) {
+		return value + " as " + alias
+	}
+
+	// If we are wrapping a table we need to prefix the alias with the table
+	// prefix as well, so the qualified columns using the alias match it.
+	if prefixAlias {
+		alias = g.tablePrefix + alias
+	}
+
+	return g.Wrap(value, false) + " as " + g.self().wrapValue(alias)
+}
+
+func (g *BaseGrammar) wrapSegments(segments []string) string {
+	for key, segment := range segments {
+		if key == 0 && len(segments) > 1 {
+			segments[key] = g.WrapTable(segment)
+		} else {
+			segments[key] = g.self().wrapValue(segment)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// wrapColumns Wrap the given columns in keyword identifiers.
+func (g *BaseGrammar) wrapColumns(columns []string) string {
+	var wrapped []string
+	for _, column := range columns {
+		wrapped = append(wrapped, g.Wrap(column, false))
+	}
+	return strings.Join(wrapped, ", ")
+}
+
+// wrapValue Wrap a single string in the standard double quote identifiers.
+func (g *BaseGrammar) wrapValue(value string) string {
+	if value != "*" {
+		return `"` + strings.Replace(value, `"`, `""`, -1) + `"`
+	}
+	return value
+}
+
+func (g *BaseGrammar) compileComponents(query *query.Query) []string {
+	var sql []string
+	for _, component := range selectComponents {
+		switch component {
+		case "aggregate":
+			if query.Aggregate != nil {
+				sql = append(sql, g.compileAggregate(query, query.Aggregate))
+			}
+		case "columns":
+			if len(query.Columns) > 0 {
+				sql = append(sql, g.self().compileColumns(query, query.Columns))
+			}
+		case "from":
+			if len(query.From) > 0 {
+				sql = append(sql, g.self().compileFrom(query, query.From))
+			}
+		case "joins":
+			if len(query.Joins) > 0 {
+				sql = append(sql, g.compileJoins(query, query.Joins))
+			}
+		case "wheres":
+			if len(query.Wheres) > 0 {
+				sql = append(sql, g.compileWheres(query))
+			}
+		case "groups":
+			if len(query.Groups) > 0 {
+				sql = append(sql, g.compileGroups(query, query.Groups))
+			}
+		case "havings":
+			if len(query.Havings) > 0 {
+				sql = append(sql, g.compileHavings(query, query.Havings))
+			}
+		case "orders":
+			if len(query.Orders) > 0 {
+				sql = append(sql, g.compileOrders(query, query.Orders))
+			}
+
+		case "limit":
+			if query.Limit > 0 {
+				sql = append(sql, g.self().compileLimit(query, query.Limit))
+			}
+		case "offset":
+			if query.Limit > 0 || query.Offset > 0 {
+				sql = append(sql, g.self().compileOffset(query, query.Offset))
+			}
+		case "lock":
+			if query.Lock != nil {
+				sql = append(sql, g.self().compileLock(query, query.Lock))
+			}
+		}
+	}
+	return sql
+}
+
+func (g *BaseGrammar) concatenate(segments []string) string {
+	s := ""
+	for _, segment := range segments {
+		if len(segment) == 0 {
+			continue
+		}
+		if len(s) > 0 {
+			s = s + " "
+		}
+		s = s + segment
+	}
+	return s
+}
+
+func (g *BaseGrammar) compileAggregate(query *query.Query, aggregate *query.Aggregate) string {
+	column := g.wrapColumns(aggregate.Columns)
+	if query.Distinct && column != "*" {
+		column = "DISTINCT " + column
+	}
+
+	return "SELECT " + aggregate.Function + "(" + column + ") AS aggregate"
+}
+
+func (g *BaseGrammar) compileColumns(query *query.Query, columns []string) string {
+	// If the query is actually performing an aggregating select, we will let that
+	// compiler handle the building of the select clauses, as it will need some
+	// more syntax that is best handled by that function to keep things neat.
+	if query.Aggregate != nil {
+		return ""
+	}
+
+	sel := "SELECT "
+	if query.Distinct {
+		sel = "SELECT DISTINCT "
+	}
+
+	return sel + g.wrapColumns(columns)
+}
+
+func (g *BaseGrammar) compileFrom(query *query.Query, table string) string {
+	if query.FromQuery != nil {
+		return "FROM (" + g.CompileSelect(query.FromQuery) + ") as " + g.WrapTable(table)
+	}
+	return "FROM " + g.WrapTable(table)
+}
+
+// compileLock Compile the lock into SQL, only the raw locks are supported by default.
+func (g *BaseGrammar) compileLock(query *query.Query, lock *query.Lock) string {
+	if lock.Type == "raw" {
+		return lock.Sql
+	}
+	return ""
+}
+
+func (g *BaseGrammar) compileJoins(query *query.Query, joins []*query.Join) string {
+	var segments []string
+	for _, join := range joins {
+		table := g.WrapTable(join.Table)
+		segments = append(segments, strings.TrimSpace(join.Type+" JOIN "+table+" "+g.compileWheres(join.Query)))
+	}
+	return strings.Join(segments, " ")
+}
+
+func (g *BaseGrammar) compileWheres(query *query.Query) string {
+
+	sql := g.compileWheresToArray(query)
+	if len(sql) > 0 {
+		return g.concatenateWhereClauses(query, sql)
+	}
+
+	return ""
+}
+
+func (g *BaseGrammar) compileWheresToArray(query *query.Query) []string {
+	var sql []string
+	for _, where := range query.Wheres {
+		w := ""
+		switch where.Type {
+		case "Basic":
+			w = where.Boolean + " " + g.whereBasic(query, where)
+		case "In":
+			w = where.Boolean + " " + g.whereIn(query, where)
+		case "NotIn":
+			w = where.Boolean + " " + g.whereNotIn(query, where)
+		case "Null":
+			w = where.Boolean + " " + g.whereNull(query, where)
+		case "NotNull":
+			w = where.Boolean + " " + g.whereNotNull(query, where)
+		case "Between":
+			w = where.Boolean + " " + g.whereBetween(query, where)
+		case "Column":
+			w = where.Boolean + " " + g.whereColumn(query, where)
+		case "Raw":
+			w = where.Boolean + " " + where.Sql
+		case "Nested":
+			w = where.Boolean + " " + g.whereNested(query, where)
+		case "InSub":
+			w = where.Boolean + " " + g.Wrap(where.Column, false) + " IN (" + g.CompileSelect(where.Query) + ")"
+		case "NotInSub":
+			w = where.Boolean + " " + g.Wrap(where.Column, false) + " NOT IN (" + g.CompileSelect(where.Query) + ")"
+		case "Exists":
+			w = where.Boolean + " EXISTS (" + g.CompileSelect(where.Query) + ")"
+		case "NotExists":
+			w = where.Boolean + " NOT EXISTS (" + g.CompileSelect(where.Query) + ")"
+
+		}
+		sql = append(sql, w)
+	}
+	return sql
+}
+
+func (g *BaseGrammar) concatenateWhereClauses(query *query.Query, sql []string) string {
+	conjunction := "WHERE"
+	if query.JoinClause {
+		conjunction = "ON"
+	}
+	return conjunction + " " + removeLeadingBoolean(strings.Join(sql, " "))
+}
+
+func (g *BaseGrammar) whereBasic(query *query.Query, where *query.Where) string {
+	// value = where.Value
+	return g.Wrap(where.Column, false) + " " + where.Operator + " " + "?"
+}
+
+// whereNested Compile a nested where clause.
+func (g *BaseGrammar) whereNested(query *query.Query, where *query.Where) string {
+	return "(" + removeLeadingBoolean(strings.Join(g.compileWheresToArray(where.Query), " ")) + ")"
+}
+
+func (g *BaseGrammar) whereIn(query *query.Query, where *query.Where) string {
+	if len(where.Values) > 0 {
+		return g.Wrap(where.Column, false) + " IN (" + g.Parameterize(where.Values) + ")"
+	}
+	return "0 = 1"
+}
+
+func (g *BaseGrammar) whereNotIn(query *query.Query, where *query.Where) string {
+	if len(where.Values) > 0 {
+		return g.Wrap(where.Column, false) + " NOT IN (" + g.Parameterize(where.Values) + ")"
+	}
+	return "1 = 1"
+}
+
+func (g *BaseGrammar) whereNull(query *query.Query, where *query.Where) string {
+	return g.Wrap(where.Column, false) + " IS NULL"
+}
+
+func (g *BaseGrammar) whereNotNull(query *query.Query, where *query.Where) string {
+	return g.Wrap(where.Column, false) + " IS NOT NULL"
+}
+
+func (g *BaseGrammar) whereBetween(query *query.Query, where *query.Where) string {
+	between := "between"
+	if where.Not {
+		between = "not between"
+	}
+
+	return g.Wrap(where.Column, false) + " " + between + " ? and ?"
+}
+
+func (g *BaseGrammar) whereColumn(query *query.Query, where *query.Where) string {
+	return g.Wrap(where.First, false) + " " + where.Operator + " " + g.Wrap(where.Second, false)
+}
+
+func (g *BaseGrammar) compileGroups(query *query.Query, groups []string) string {
+	return "GROUP BY " + g.wrapColumns(groups)
+}
+
+func (g *BaseGrammar) compileHavings(query *query.Query, havings []*query.Having) string {
+	sqls := make([]string, 0)
+	for _, having := range havings {
+		sqls = append(sqls, g.compileHaving(having))
+	}
+	sql := strings.Join(sqls, " ")
+	return "HAVING " + removeLeadingBoolean(sql)
+}
+
+func (g *BaseGrammar) compileHaving(having *query.Having) string {
+	if having.Type == "Raw" {
+		return having.Boolean + " " + having.Sql
+	}
+	return g.compileBasicHaving(having)
+}
+
+func (g *BaseGrammar) compileBasicHaving(having *query.Having) string {
+	return having.Boolean + " " + g.Wrap(having.Column, false) + " " + having.Operator + " " + "?"
+}
+
+func (g *BaseGrammar) compileOrders(query *query.Query, orders []*query.Order) string {
+	var sql []string
+
+	for _, order := range orders {
+		s := ""
+		if len(order.Sql) > 0 {
+			s = order.Sql
+		} else {
+			s = g.Wrap(order.Column, false) + " " + order.Direction
+		}
+		sql = append(sql, s)
+	}
+
+	if len(sql) == 0 {
+		return ""
+	}
+
+	return "ORDER BY " + strings.Join(sql, ", ")
+}
+
+func (g *BaseGrammar) compileLimit(query *query.Query, limit uint64) string {
+	return fmt.Sprintf("LIMIT %v", limit)
+}
+
+func (g *BaseGrammar) compileOffset(query *query.Query, offset uint64) string {
+	if query.Limit == 0 {
+		return ""
+	}
+	return fmt.Sprintf("OFFSET %v", offset)
+}
+
+// CompileInsert Compile an insert statement into SQL.
+func (g *BaseGrammar) CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	columns, rows, bindings, err := g.compileInsertValues(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) values %s", g.WrapTable(query.From), g.wrapColumns(columns), strings.Join(rows, ",")), bindings, nil
+}
+
+// compileInsertValues Compile the sorted columns and the value tuples of the
+// inserted rows, the expressions are inlined and the columns missing from a row
+// are filled with the FillMissing keyword of the query.
+func (g *BaseGrammar) compileInsertValues(query *query.Query, values []map[string]interface{}) ([]string, []string, []interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil, nil, errors.New("There are no values to insert.")
+	}
+
+	keys := make(map[string]interface{})
+	for _, row := range values {
+		for column := range row {
+			keys[column] = nil
+		}
+	}
+	columns := sortedKeys(keys)
+	if len(columns) == 0 {
+		return nil, nil, nil, errors.New("There are no columns to insert.")
+	}
+
+	var rows []string
+	var bindings []interface{}
+
+	for i, row := range values {
+		var parameters []string
+		for _, column := range columns {
+			value, ok := row[column]
+			switch {
+			case !ok && query.FillMissing == "":
+				return nil, nil, nil, fmt.Errorf("The row [%d] to insert has no value for the column [%s].", i, column)
+			case !ok:
+				parameters = append(parameters, query.FillMissing)
+			case g.IsExpression(value):
+				parameters = append(parameters, fmt.Sprint(g.Parameter(value)))
+			default:
+				parameters = append(parameters, "?")
+				bindings = append(bindings, value)
+			}
+		}
+		rows = append(rows, "("+strings.Join(parameters, ", ")+")")
+	}
+
+	return columns, rows, bindings, nil
+}
+
+// CompileInsertUsing Compile an insert statement using a subquery into SQL.
+func (g *BaseGrammar) CompileInsertUsing(query *query.Query, columns []string, source *query.Query) string {
+	table := g.WrapTable(query.From)
+	if len(columns) > 0 {
+		table += " (" + g.wrapColumns(columns) + ")"
+	}
+
+	return "INSERT INTO " + table + " " + g.CompileSelect(source)
+}
+
+// CompileInsertOrIgnore Compile an insert ignore statement into SQL.
+func (g *BaseGrammar) CompileInsertOrIgnore(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	return "", nil, errors.New("This database engine does not support inserting while ignoring errors.")
+}
+
+// CompileUpsert Compile an "upsert" statement into SQL.
+func (g *BaseGrammar) CompileUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error) {
+	return "", nil, errors.New("This database engine does not support upserts.")
+}
+
+// compileOnConflictUpsert Compile an "upsert" statement with the "on conflict"
+// clause of SQLite and PostgreSQL.
+func (g *BaseGrammar) compileOnConflictUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error) {
+	if len(uniqueBy) == 0 {
+		return "", nil, errors.New("Upsert requires at least one unique column.")
+	}
+
+	sql, bindings, err := g.CompileInsert(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var columns []string
+	for _, column := range update {
+		columns = append(columns, g.Wrap(column, false)+" = excluded."+g.Wrap(column, false))
+	}
+
+	return sql + " ON CONFLICT (" + g.wrapColumns(uniqueBy) + ") DO UPDATE SET " + strings.Join(columns, ", "), bindings, nil
+}
+
+// CompileUpdate Compile an update statement into SQL.
+func (g *BaseGrammar) CompileUpdate(query *query.Query, values map[string]interface{}) string {
+	table := g.WrapTable(query.From)
+	columns := g.compileUpdateColumns(values)
+
+	joins := ""
+	if len(query.Joins) > 0 {
+		joins = " " + g.compileJoins(query, query.Joins)
+	}
+
+	where := g.compileWheres(query)
+
+	return strings.TrimSpace(fmt.Sprintf("UPDATE %s%s SET %s %s", table, joins, columns, where))
+}
+
+// compileUpdateColumns Compile all of the columns for an update statement.
+func (g *BaseGrammar) compileUpdateColumns(values map[string]interface{}) string {
+	var columns []string
+
+	for _, key := range sortedKeys(values) {
+		columns = append(columns, fmt.Sprintf("%s = %s", g.Wrap(key, false), g.Parameter(values[key])))
+	}
+	return strings.Join(columns, ", ")
+}
+
+// compileUpdateWithJoinsOrLimit Compile an update statement restricted to the
+// rows selected by the query, for databases without joins or limits on updates.
+func (g *BaseGrammar) compileUpdateWithJoinsOrLimit(query *query.Query, values map[string]interface{}, key string) string {
+	return fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s",
+		g.WrapTable(query.From),
+		g.compileUnqualifiedUpdateColumns(values),
+		g.compileKeyIn(query, key),
+	)
+}
+
+// compileUnqualifiedUpdateColumns Compile the columns for an update statement
+// without their table names.
+func (g *BaseGrammar) compileUnqualifiedUpdateColumns(values map[string]interface{}) string {
+	var columns []string
+
+	for _, key := range sortedKeys(values) {
+		segments := strings.Split(key, ".")
+		column := segments[len(segments)-1]
+		columns = append(columns, fmt.Sprintf("%s = %s", g.Wrap(column, false), g.Parameter(values[key])))
+	}
+	return strings.Join(columns, ", ")
+}
+
+// compileKeyIn Compile a constraint on the key column selecting the rows matched by the query.
+func (g *BaseGrammar) compileKeyIn(query *query.Query, key string) string {
+	alias := tableAlias(g.WrapTable(query.From))
+
+	sub := *query
+	sub.Columns = []string{alias + "." + key}
+	sub.Aggregate = nil
+
+	return key + " IN (" + g.CompileSelect(&sub) + ")"
+}
+
+// CompileDelete Compile a delete statement into SQL.
+func (g *BaseGrammar) CompileDelete(query *query.Query) string {
+	table := g.WrapTable(query.From)
+	where := ""
+
+	if len(query.Wheres) > 0 {
+		where = g.compileWheres(query)
+	}
+
+	if len(query.Joins) > 0 {
+		return g.compileDeleteWithJoins(query, table, where)
+	} else {
+		return g.compileDeleteWithoutJoins(query, table, where)
+	}
+}
+
+// compileDeleteWithoutJoins Compile a delete query that does not use joins.
+func (g *BaseGrammar) compileDeleteWithoutJoins(query *query.Query, table string, where string) string {
+	return strings.TrimSpace(fmt.Sprintf("DELETE FROM %s %s", table, where))
+}
+
+// compileDeleteWithJoinsOrLimit Compile a delete statement restricted to the
+// rows selected by the query, for databases without joins or limits on deletes.
+func (g *BaseGrammar) compileDeleteWithJoinsOrLimit(query *query.Query, key string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", g.WrapTable(query.From), g.compileKeyIn(query, key))
+}
+
+// compileDeleteWithJoins Compile a delete query that uses joins.
+func (g *BaseGrammar) compileDeleteWithJoins(query *query.Query, table string, where string) string {
+	joins := " " + g.compileJoins(query, query.Joins)
+
+	alias := tableAlias(table)
+
+	return strings.TrimSpace(fmt.Sprintf("DELETE %s FROM %s%s %s", alias, table, joins, where))
 }
 
 func (g *BaseGrammar) Parameterize(values []interface{}) string {
@@ -30,35 +759,256 @@ func (g *BaseGrammar) IsExpression(value interface{}) bool {
 	return ok
 }
 
+// CompileSavepoint Compile the SQL statement to define a savepoint.
+func (g *BaseGrammar) CompileSavepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+// CompileSavepointRollBack Compile the SQL statement to execute a savepoint rollback.
+func (g *BaseGrammar) CompileSavepointRollBack(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+// CompileSavepointRelease Compile the SQL statement to release a savepoint.
+func (g *BaseGrammar) CompileSavepointRelease(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
 // PrepareBindingsForUpdate Prepare the bindings for an update statement.
 func (g *BaseGrammar) PrepareBindingsForUpdate(bindings map[string][]interface{}, values map[string]interface{}) []interface{} {
+	results := FlattenBindings(bindings, "join")
+
+	for _, key := range sortedKeys(values) {
+		results = append(results, values[key])
+	}
 
+	return append(results, FlattenBindings(bindings, "where", "having", "order", "union")...)
+}
+
+// prepareBindingsForUpdateWithJoinsOrLimit Prepare the bindings for an update
+// statement whose joins and wheres are compiled after the values.
+func (g *BaseGrammar) prepareBindingsForUpdateWithJoinsOrLimit(bindings map[string][]interface{}, values map[string]interface{}) []interface{} {
 	var results []interface{}
 
-	for _, val := range bindings["join"] {
-		results = append(results, val)
+	for _, key := range sortedKeys(values) {
+		results = append(results, values[key])
 	}
 
-	var keys []string
-	for k := range values {
-		keys = append(keys, k)
+	return append(results, FlattenBindings(bindings, "join", "where", "having", "order", "union")...)
+}
+
+// ReturnsInsertId Determine if insert statements return the inserted id as a
+// result set, of the Returning column of the query, instead of reporting it
+// through the driver.
+func (g *BaseGrammar) ReturnsInsertId() bool {
+	return false
+}
+
+// ReportsFirstInsertId Determine if the id reported for an insert of several
+// rows is the id of its first row, as MySQL does, instead of the last one.
+func (g *BaseGrammar) ReportsFirstInsertId() bool {
+	return false
+}
+
+// MaxParameters Get the maximum number of place-holders of a statement, the
+// default is the limit of the SQLite versions before 3.32.
+func (g *BaseGrammar) MaxParameters() int {
+	return 999
+}
+
+// MaxInsertRows Get the maximum number of rows of an insert statement, zero
+// means unlimited.
+func (g *BaseGrammar) MaxInsertRows() int {
+	return 0
+}
+
+// SubstituteBindingsIntoRawSql Substitute the given bindings into the given
+// raw SQL query, for debugging purposes only.
+func (g *BaseGrammar) SubstituteBindingsIntoRawSql(sql string, bindings []interface{}) string {
+	return g.substituteBindings(sql, bindings, "")
+}
+
+// substituteBindings Replace the place-holders outside of quoted strings and
+// identifiers with the escaped bindings, the place-holders are "?" when the
+// prefix is empty and the prefix followed by the binding number otherwise.
+func (g *BaseGrammar) substituteBindings(sql string, bindings []interface{}, prefix string) string {
+	var b strings.Builder
+	var quote byte
+	n := 0
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case prefix == "" && c == '?':
+			if n < len(bindings) {
+				b.WriteString(g.escape(bindings[n]))
+				n++
+				continue
+			}
+		case prefix != "" && strings.HasPrefix(sql[i:], prefix):
+			j := i + len(prefix)
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if number, err := strconv.Atoi(sql[i+len(prefix) : j]); err == nil && number > 0 && number <= len(bindings) {
+				b.WriteString(g.escape(bindings[number-1]))
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
 	}
-	sort.Strings(keys)
+	return b.String()
+}
 
-	for _, key := range keys {
-		results = append(results, values[key])
+// escape Get the SQL literal of a binding.
+func (g *BaseGrammar) escape(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(valuer); v.Kind() == reflect.Ptr && v.IsNil() {
+			return "NULL"
+		}
+		v, err := valuer.Value()
+		if err != nil {
+			return "NULL"
+		}
+		value = v
 	}
 
-	for kb, vb := range bindings {
-		if kb == "join" {
-			continue
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case Expression:
+		return fmt.Sprint(v.GetValue())
+	case string:
+		return g.self().escapeString(v)
+	case []byte:
+		if v == nil {
+			return "NULL"
 		}
-		if kb == "select" {
-			continue
+		return g.self().escapeBinary(v)
+	case bool:
+		return g.self().escapeBool(v)
+	case time.Time:
+		return g.self().escapeString(v.Format("2006-01-02 15:04:05.999999"))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "NULL"
 		}
-		for _, val := range vb {
-			results = append(results, val)
+		return g.escape(rv.Elem().Interface())
+	}
+
+	return g.self().escapeString(fmt.Sprint(value))
+}
+
+// escapeString Quote a string literal for the raw SQL.
+func (g *BaseGrammar) escapeString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// escapeBinary Quote a binary literal for the raw SQL.
+func (g *BaseGrammar) escapeBinary(value []byte) string {
+	return "X'" + hex.EncodeToString(value) + "'"
+}
+
+// escapeBool Quote a boolean literal for the raw SQL.
+func (g *BaseGrammar) escapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// numberParameters Replace the "?" place-holders outside of quoted strings and
+// identifiers with the numbered place-holders of the database.
+func numberParameters(sql string, placeholder func(n int) string) string {
+	var b strings.Builder
+	var quote rune
+	n := 0
+
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString(placeholder(n))
+			continue
 		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unionPaging Get a query holding the union orders, limit and offset of the query.
+func unionPaging(parent *query.Query) *query.Query {
+	return &query.Query{
+		Orders: unionOrders(parent.UnionOrders),
+		Limit:  parent.UnionLimit,
+		Offset: parent.UnionOffset,
+	}
+}
+
+// unionOrders Get the union orders as the orders of a query.
+func unionOrders(orders []*query.UnionOrder) []*query.Order {
+	var results []*query.Order
+	for _, order := range orders {
+		results = append(results, &query.Order{
+			Type:      order.Type,
+			Sql:       order.Sql,
+			Column:    order.Column,
+			Direction: order.Direction,
+		})
 	}
 	return results
 }
+
+// tableAlias Get the alias of a table, or the table itself when it has no alias.
+func tableAlias(table string) string {
+	if i := strings.LastIndex(strings.ToLower(table), " as "); i >= 0 {
+		return strings.TrimSpace(table[i+4:])
+	}
+	return table
+}
+
+// sortedKeys Get the keys of the values in a stable order.
+func sortedKeys(values map[string]interface{}) []string {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func removeLeadingBoolean(value string) string {
+	reg := regexp.MustCompile(`(?i:and |or )`)
+	n := 0
+	b := reg.ReplaceAllFunc([]byte(value), func(bytes []byte) []byte {
+		n = n + 1
+		if n > 1 {
+			return bytes
+		}
+		return []byte("")
+	})
+	return string(b)
+	// return reg.ReplaceAllString(value, "")
+}
@@ -6,8 +6,23 @@ type Grammar interface {
 	// CompileSelect Compile a select query into SQL.
 	CompileSelect(query *query.Query) string
 
-	// CompileInsert Compile an insert statement into SQL.
-	CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{})
+	// CompileSubSelect Compile a select query into SQL embedded in another query,
+	// the place-holders are numbered when the outer query is compiled.
+	CompileSubSelect(query *query.Query) string
+
+	// CompileInsert Compile an insert statement into SQL, the rows must have the
+	// same columns unless the FillMissing keyword of the query is set.
+	CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{}, error)
+
+	// CompileInsertUsing Compile an insert statement using a subquery into SQL.
+	CompileInsertUsing(query *query.Query, columns []string, source *query.Query) string
+
+	// CompileInsertOrIgnore Compile an insert ignore statement into SQL.
+	CompileInsertOrIgnore(query *query.Query, values []map[string]interface{}) (string, []interface{}, error)
+
+	// CompileUpsert Compile an "upsert" statement into SQL, the rows conflicting
+	// on the uniqueBy columns have their update columns updated.
+	CompileUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error)
 
 	// CompileUpdate Compile an update statement into SQL.
 	CompileUpdate(query *query.Query, values map[string]interface{}) string
@@ -15,6 +30,42 @@ type Grammar interface {
 	// CompileDelete Compile a delete statement into SQL.
 	CompileDelete(query *query.Query) string
 
+	// CompileSavepoint Compile the SQL statement to define a savepoint.
+	CompileSavepoint(name string) string
+
+	// CompileSavepointRollBack Compile the SQL statement to execute a savepoint rollback.
+	CompileSavepointRollBack(name string) string
+
+	// CompileSavepointRelease Compile the SQL statement to release a savepoint,
+	// empty when the database releases the savepoints with the transaction.
+	CompileSavepointRelease(name string) string
+
 	// PrepareBindingsForUpdate Prepare the bindings for an update statement.
 	PrepareBindingsForUpdate(bindings map[string][]interface{}, values map[string]interface{}) []interface{}
+
+	// Wrap Wrap a value in keyword identifiers.
+	Wrap(value string, prefixAlias bool) string
+
+	// WrapTable Wrap a table in keyword identifiers, prefixing the table and its alias.
+	WrapTable(table string) string
+
+	// ReturnsInsertId Determine if insert statements return the inserted id as a
+	// result set, of the Returning column of the query, instead of reporting it
+	// through the driver.
+	ReturnsInsertId() bool
+
+	// ReportsFirstInsertId Determine if the id reported for an insert of several
+	// rows is the id of its first row, as MySQL does, instead of the last one.
+	ReportsFirstInsertId() bool
+
+	// MaxParameters Get the maximum number of place-holders of a statement.
+	MaxParameters() int
+
+	// MaxInsertRows Get the maximum number of rows of an insert statement, zero
+	// means unlimited.
+	MaxInsertRows() int
+
+	// SubstituteBindingsIntoRawSql Substitute the given bindings into the given
+	// raw SQL query, for debugging purposes only.
+	SubstituteBindingsIntoRawSql(sql string, bindings []interface{}) string
 }
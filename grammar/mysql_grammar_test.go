@@ -0,0 +1,293 @@
+package grammar
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/thinkoner/torm/query"
+)
+
+func TestMySqlGrammar_CompileSelect(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{
+		From: "users",
+		Wheres: []*query.Where{
+			{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"},
+			{Type: "In", Column: "addr", Values: []interface{}{"Columbia", "Alaska"}, Boolean: "and"},
+			{Type: "Column", First: "users.created_at", Operator: "!=", Second: "users.updated_at", Boolean: "and"},
+		},
+		Orders: []*query.Order{{Column: "name", Direction: "ASC"}},
+		Limit:  10,
+		Offset: 5,
+	}
+
+	expected := "SELECT * FROM `users` WHERE `gender` = ? and `addr` IN (?, ?) and `users`.`created_at` != `users`.`updated_at` ORDER BY `name` ASC LIMIT 10 OFFSET 5"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_CompileUpdate(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Orders: []*query.Order{{Column: "id", Direction: "DESC"}},
+		Limit:  1,
+	}
+
+	expected := "UPDATE `users` SET `addr` = ?, `name` = ? WHERE `gender` = ? ORDER BY `id` DESC LIMIT 1"
+	sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew", "addr": "Alaska"})
+	if sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_CompileDelete(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Limit:  1,
+	}
+
+	expected := "DELETE FROM `users` WHERE `gender` = ? LIMIT 1"
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+
+	q = &query.Query{
+		From: "users",
+		Joins: []*query.Join{{
+			Type:  "INNER",
+			Table: "contacts",
+			Query: &query.Query{
+				JoinClause: true,
+				Wheres:     []*query.Where{{Type: "Column", First: "users.id", Operator: "=", Second: "contacts.user_id", Boolean: "and"}},
+			},
+		}},
+	}
+
+	expected = "DELETE `users` FROM `users` INNER JOIN `contacts` ON `users`.`id` = `contacts`.`user_id`"
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_CompileInsertOrIgnore(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{From: "users"}
+
+	sql, bindings, err := g.CompileInsertOrIgnore(q, []map[string]interface{}{{"name": "Andrew"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "INSERT IGNORE INTO `users` (`name`) values (?)"
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+	if len(bindings) != 1 || bindings[0] != "Andrew" {
+		t.Errorf("Expected the bindings to be [Andrew] but instead got %v !", bindings)
+	}
+}
+
+func TestMySqlGrammar_CompileSavepoint(t *testing.T) {
+	g := NewMySqlGrammar("")
+
+	if sql := g.CompileSavepoint("trans2"); sql != "SAVEPOINT trans2" {
+		t.Errorf("Expected the compiled savepoint to be %s but instead got %s !", "SAVEPOINT trans2", sql)
+	}
+	if sql := g.CompileSavepointRollBack("trans2"); sql != "ROLLBACK TO SAVEPOINT trans2" {
+		t.Errorf("Expected the compiled rollback to be %s but instead got %s !", "ROLLBACK TO SAVEPOINT trans2", sql)
+	}
+	if sql := g.CompileSavepointRelease("trans2"); sql != "RELEASE SAVEPOINT trans2" {
+		t.Errorf("Expected the compiled release to be %s but instead got %s !", "RELEASE SAVEPOINT trans2", sql)
+	}
+}
+
+func TestMySqlGrammar_TablePrefix(t *testing.T) {
+	g := NewMySqlGrammar("t1_")
+	q := &query.Query{
+		Columns: []string{"u.name", "c.*", "count(c.id) as total"},
+		From:    "users as u",
+		Joins: []*query.Join{{
+			Type:  "LEFT",
+			Table: "contacts as c",
+			Query: &query.Query{
+				JoinClause: true,
+				Wheres:     []*query.Where{{Type: "Column", First: "u.id", Operator: "=", Second: "c.user_id", Boolean: "and"}},
+			},
+		}},
+		Wheres: []*query.Where{
+			{Type: "Basic", Column: "u.gender", Operator: "=", Value: "M", Boolean: "and"},
+			{Type: "Column", First: "u.created_at", Operator: "!=", Second: "u.updated_at", Boolean: "and"},
+		},
+	}
+
+	expected := "SELECT `t1_u`.`name`, `t1_c`.*, count(c.id) as total FROM `t1_users` as `t1_u` LEFT JOIN `t1_contacts` as `t1_c` ON `t1_u`.`id` = `t1_c`.`user_id` WHERE `t1_u`.`gender` = ? and `t1_u`.`created_at` != `t1_u`.`updated_at`"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+
+	expected = "DELETE `t1_u` FROM `t1_users` as `t1_u` LEFT JOIN `t1_contacts` as `t1_c` ON `t1_u`.`id` = `t1_c`.`user_id` WHERE `t1_u`.`gender` = ? and `t1_u`.`created_at` != `t1_u`.`updated_at`"
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+
+	q = &query.Query{From: "users"}
+	sql, _, _ := g.CompileInsert(q, []map[string]interface{}{{"name": "Andrew"}})
+	expected = "INSERT INTO `t1_users` (`name`) values (?)"
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+
+	expected = "UPDATE `t1_users` SET `t1_users`.`name` = ?"
+	if sql := g.CompileUpdate(q, map[string]interface{}{"users.name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_SubstituteBindingsIntoRawSql(t *testing.T) {
+	g := NewMySqlGrammar("")
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := "O'Brien \\ co"
+
+	sql := g.SubstituteBindingsIntoRawSql(
+		"SELECT * FROM `users` WHERE `name` = ? and `note` != '?' and `created_at` > ? and `avatar` = ? and `deleted_at` IS ? and `id` IN (?, ?)",
+		[]interface{}{&name, created, []byte{0xde, 0xad}, nil, 1, 2.5},
+	)
+
+	expected := "SELECT * FROM `users` WHERE `name` = 'O\\'Brien \\\\ co' and `note` != '?' and `created_at` > '2020-01-02 03:04:05' and `avatar` = X'dead' and `deleted_at` IS NULL and `id` IN (1, 2.5)"
+	if sql != expected {
+		t.Errorf("Expected the raw sql to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_PrepareBindingsForUpdate(t *testing.T) {
+	g := NewMySqlGrammar("")
+	bindings := map[string][]interface{}{
+		"select": {"ignored"},
+		"join":   {1},
+		"where":  {4},
+		"order":  {5},
+	}
+
+	for i := 0; i < 100; i++ {
+		results := g.PrepareBindingsForUpdate(bindings, map[string]interface{}{"b": 3, "a": 2})
+		if fmt.Sprint(results) != "[1 2 3 4 5]" {
+			t.Fatalf("Expected the bindings to be [1 2 3 4 5] but instead got %v !", results)
+		}
+	}
+}
+
+func TestMySqlGrammar_CompileUnion(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Unions: []*query.Union{
+			{Query: &query.Query{From: "admins"}},
+			{Query: &query.Query{From: "guests", Limit: 5}, All: true},
+		},
+		UnionOrders: []*query.UnionOrder{{Column: "name", Direction: "DESC"}},
+		UnionLimit:  10,
+		UnionOffset: 20,
+	}
+
+	expected := "(SELECT * FROM `users` WHERE `gender` = ?) union (SELECT * FROM `admins`) union all (SELECT * FROM `guests` LIMIT 5 OFFSET 0) ORDER BY `name` DESC LIMIT 10 OFFSET 20"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled union to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Aggregate = &query.Aggregate{Function: "count", Columns: []string{"*"}}
+	q.UnionOrders, q.UnionLimit, q.UnionOffset = nil, 0, 0
+
+	expected = "SELECT count(*) AS aggregate FROM ((SELECT * FROM `users` WHERE `gender` = ?) union (SELECT * FROM `admins`) union all (SELECT * FROM `guests` LIMIT 5 OFFSET 0)) as `temp_table`"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled union aggregate to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_CompileLock(t *testing.T) {
+	g := NewMySqlGrammar("")
+	locks := map[string]*query.Lock{
+		"FOR UPDATE":             {Type: "update"},
+		"FOR UPDATE SKIP LOCKED": {Type: "update", Option: "SKIP LOCKED"},
+		"LOCK IN SHARE MODE":     {Type: "share"},
+		"FOR SHARE NOWAIT":       {Type: "share", Option: "NOWAIT"},
+	}
+
+	for lock, l := range locks {
+		q := &query.Query{From: "jobs", Limit: 1, Lock: l}
+		expected := "SELECT * FROM `jobs` LIMIT 1 OFFSET 0 " + lock
+		if sql := g.CompileSelect(q); sql != expected {
+			t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+		}
+	}
+}
+
+func TestMySqlGrammar_CompileNestedWhere(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{
+		From: "users",
+		Wheres: []*query.Where{
+			{Type: "Basic", Column: "a", Operator: "=", Value: 1, Boolean: "and"},
+			{Type: "Nested", Boolean: "and", Query: &query.Query{Wheres: []*query.Where{
+				{Type: "Basic", Column: "b", Operator: "=", Value: 2, Boolean: "and"},
+				{Type: "Raw", Sql: "c = ?", Boolean: "or"},
+			}}},
+		},
+	}
+
+	expected := "SELECT * FROM `users` WHERE `a` = ? and (`b` = ? or c = ?)"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestMySqlGrammar_CompileUpsert(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{From: "products"}
+
+	sql, bindings, err := g.CompileUpsert(q, []map[string]interface{}{{"sku": "a"}, {"sku": "b"}}, []string{"sku"}, []string{"sku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "INSERT INTO `products` (`sku`) values (?),(?) ON DUPLICATE KEY UPDATE `sku` = VALUES(`sku`)"
+	if sql != expected {
+		t.Errorf("Expected the compiled upsert to be %s but instead got %s !", expected, sql)
+	}
+	if len(bindings) != 2 {
+		t.Errorf("Expected 2 bindings but instead got %v !", bindings)
+	}
+}
+
+func TestMySqlGrammar_CompileInsert(t *testing.T) {
+	g := NewMySqlGrammar("")
+	q := &query.Query{From: "users"}
+	values := []map[string]interface{}{
+		{"name": "Andrew", "gender": "M", "created_at": query.Expr("now()")},
+		{"name": "Boston", "created_at": query.Expr("now()")},
+	}
+
+	if _, _, err := g.CompileInsert(q, values); err == nil {
+		t.Error("Expect: the rows with different columns should fail")
+	}
+
+	q.FillMissing = "DEFAULT"
+	sql, bindings, err := g.CompileInsert(q, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "INSERT INTO `users` (`created_at`, `gender`, `name`) values (now(), ?, ?),(now(), DEFAULT, ?)"
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+	if fmt.Sprint(bindings) != "[M Andrew Boston]" {
+		t.Errorf("Expected the bindings to be [M Andrew Boston] but instead got %v !", bindings)
+	}
+}
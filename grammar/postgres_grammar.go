@@ -0,0 +1,133 @@
+package grammar
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/thinkoner/torm/query"
+)
+
+type PostgresGrammar struct {
+	BaseGrammar
+}
+
+// NewPostgresGrammar Create a new PostgreSQL query grammar.
+func NewPostgresGrammar(tablePrefix string) *PostgresGrammar {
+	g := &PostgresGrammar{}
+	g.tablePrefix = tablePrefix
+	g.dialect = g
+	return g
+}
+
+// CompileSelect Compile a select query into SQL.
+func (g *PostgresGrammar) CompileSelect(query *query.Query) string {
+	return g.numberParameters(g.BaseGrammar.CompileSelect(query))
+}
+
+// compileLock Compile the lock into SQL.
+func (g *PostgresGrammar) compileLock(query *query.Query, lock *query.Lock) string {
+	switch lock.Type {
+	case "update":
+		return strings.TrimSpace("FOR UPDATE " + lock.Option)
+	case "share":
+		return strings.TrimSpace("FOR SHARE " + lock.Option)
+	}
+	return lock.Sql
+}
+
+// CompileInsert Compile an insert statement into SQL.
+func (g *PostgresGrammar) CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	sql, bindings, err := g.BaseGrammar.CompileInsert(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+	return g.numberParameters(sql + g.compileReturning(query)), bindings, nil
+}
+
+// CompileInsertUsing Compile an insert statement using a subquery into SQL.
+func (g *PostgresGrammar) CompileInsertUsing(query *query.Query, columns []string, source *query.Query) string {
+	return g.numberParameters(g.BaseGrammar.CompileInsertUsing(query, columns, source))
+}
+
+// CompileInsertOrIgnore Compile an insert ignore statement into SQL.
+func (g *PostgresGrammar) CompileInsertOrIgnore(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	sql, bindings, err := g.BaseGrammar.CompileInsert(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+	return g.numberParameters(sql + " ON CONFLICT DO NOTHING"), bindings, nil
+}
+
+// CompileUpsert Compile an "upsert" statement into SQL.
+func (g *PostgresGrammar) CompileUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error) {
+	sql, bindings, err := g.compileOnConflictUpsert(query, values, uniqueBy, update)
+	if err != nil {
+		return "", nil, err
+	}
+	return g.numberParameters(sql), bindings, nil
+}
+
+// compileReturning Compile the clause returning the id of the inserted rows,
+// which is empty when the query returns no column.
+func (g *PostgresGrammar) compileReturning(query *query.Query) string {
+	if query.Returning == "" {
+		return ""
+	}
+	return " RETURNING " + g.wrapValue(query.Returning)
+}
+
+// CompileUpdate Compile an update statement into SQL.
+func (g *PostgresGrammar) CompileUpdate(query *query.Query, values map[string]interface{}) string {
+	if len(query.Joins) == 0 && query.Limit == 0 {
+		return g.numberParameters(g.BaseGrammar.CompileUpdate(query, values))
+	}
+
+	// PostgreSQL does not support joins or limits on updates, so we will select
+	// the physical row ids of the matching records and update those rows.
+	return g.numberParameters(g.compileUpdateWithJoinsOrLimit(query, values, "ctid"))
+}
+
+// PrepareBindingsForUpdate Prepare the bindings for an update statement.
+func (g *PostgresGrammar) PrepareBindingsForUpdate(bindings map[string][]interface{}, values map[string]interface{}) []interface{} {
+	return g.prepareBindingsForUpdateWithJoinsOrLimit(bindings, values)
+}
+
+// CompileDelete Compile a delete statement into SQL.
+func (g *PostgresGrammar) CompileDelete(query *query.Query) string {
+	if len(query.Joins) == 0 && query.Limit == 0 {
+		return g.numberParameters(g.BaseGrammar.CompileDelete(query))
+	}
+
+	return g.numberParameters(g.compileDeleteWithJoinsOrLimit(query, "ctid"))
+}
+
+// ReturnsInsertId Determine if insert statements return the inserted id as a
+// result set, of the Returning column of the query, instead of reporting it
+// through the driver.
+func (g *PostgresGrammar) ReturnsInsertId() bool {
+	return true
+}
+
+// SubstituteBindingsIntoRawSql Substitute the given bindings into the given
+// raw SQL query, for debugging purposes only.
+func (g *PostgresGrammar) SubstituteBindingsIntoRawSql(sql string, bindings []interface{}) string {
+	return g.substituteBindings(sql, bindings, "$")
+}
+
+// escapeBinary Quote a binary literal for the raw SQL.
+func (g *PostgresGrammar) escapeBinary(value []byte) string {
+	return "'\\x" + hex.EncodeToString(value) + "'::bytea"
+}
+
+// MaxParameters Get the maximum number of place-holders of a statement.
+func (g *PostgresGrammar) MaxParameters() int {
+	return 65535
+}
+
+// numberParameters Replace the "?" place-holders with "$1", "$2" ... "$n".
+func (g *PostgresGrammar) numberParameters(sql string) string {
+	return numberParameters(sql, func(n int) string {
+		return "$" + strconv.Itoa(n)
+	})
+}
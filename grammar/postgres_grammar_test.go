@@ -0,0 +1,162 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/thinkoner/torm/query"
+)
+
+func TestPostgresGrammar_CompileSelect(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{
+		From: "users",
+		Wheres: []*query.Where{
+			{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"},
+			{Type: "In", Column: "addr", Values: []interface{}{"Columbia", "Alaska"}, Boolean: "and"},
+			{Type: "Between", Column: "users.balance", Boolean: "and"},
+		},
+		Orders: []*query.Order{{Type: "Raw", Sql: "name = 'who?' DESC"}},
+	}
+
+	expected := `SELECT * FROM "users" WHERE "gender" = $1 and "addr" IN ($2, $3) and "users"."balance" between $4 and $5 ORDER BY name = 'who?' DESC`
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_CompileInsert(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{From: "users", Returning: "id"}
+
+	sql, bindings, _ := g.CompileInsert(q, []map[string]interface{}{{"name": "Andrew"}, {"name": "Boston"}})
+
+	expected := `INSERT INTO "users" ("name") values ($1),($2) RETURNING "id"`
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+	if len(bindings) != 2 {
+		t.Errorf("Expected 2 bindings but instead got %v !", bindings)
+	}
+
+	sql, _, _ = g.CompileInsert(&query.Query{From: "users"}, []map[string]interface{}{{"name": "Andrew"}})
+
+	expected = `INSERT INTO "users" ("name") values ($1)`
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+
+	sql, _, err := g.CompileInsertOrIgnore(q, []map[string]interface{}{{"name": "Andrew"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `INSERT INTO "users" ("name") values ($1) ON CONFLICT DO NOTHING`
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+
+	if !g.ReturnsInsertId() {
+		t.Error("Expected the postgres grammar to return the inserted ids")
+	}
+}
+
+func TestPostgresGrammar_CompileUpdate(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+	}
+
+	expected := `UPDATE "users" SET "addr" = $1, "name" = $2 WHERE "gender" = $3`
+	if sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew", "addr": "Alaska"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Limit = 1
+
+	expected = `UPDATE "users" SET "name" = $1 WHERE ctid IN (SELECT "users".ctid FROM "users" WHERE "gender" = $2 LIMIT 1 OFFSET 0)`
+	if sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_CompileDelete(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "id", Operator: "=", Value: 1, Boolean: "and"}},
+	}
+
+	expected := `DELETE FROM "users" WHERE "id" = $1`
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Joins = []*query.Join{{
+		Type:  "INNER",
+		Table: "contacts",
+		Query: &query.Query{
+			JoinClause: true,
+			Wheres:     []*query.Where{{Type: "Column", First: "users.id", Operator: "=", Second: "contacts.user_id", Boolean: "and"}},
+		},
+	}}
+
+	expected = `DELETE FROM "users" WHERE ctid IN (SELECT "users".ctid FROM "users" INNER JOIN "contacts" ON "users"."id" = "contacts"."user_id" WHERE "id" = $1)`
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_SubstituteBindingsIntoRawSql(t *testing.T) {
+	g := NewPostgresGrammar("")
+
+	sql := g.SubstituteBindingsIntoRawSql(
+		`SELECT * FROM "users" WHERE "name" = $1 and "avatar" = $2 and "active" = $3 and "id" IN ($10, $11)`,
+		[]interface{}{"O'Brien \\", []byte("ab"), true, 4, 5, 6, 7, 8, 9, 10, 11},
+	)
+
+	expected := `SELECT * FROM "users" WHERE "name" = 'O''Brien \' and "avatar" = '\x6162'::bytea and "active" = TRUE and "id" IN (10, 11)`
+	if sql != expected {
+		t.Errorf("Expected the raw sql to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_CompileLock(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{From: "jobs", Lock: &query.Lock{Type: "share", Option: "SKIP LOCKED"}}
+
+	expected := `SELECT * FROM "jobs" FOR SHARE SKIP LOCKED`
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_CompileUpsert(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{From: "products"}
+
+	sql, _, err := g.CompileUpsert(q, []map[string]interface{}{{"sku": "a"}, {"sku": "b"}}, []string{"sku"}, []string{"sku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `INSERT INTO "products" ("sku") values ($1),($2) ON CONFLICT ("sku") DO UPDATE SET "sku" = excluded."sku"`
+	if sql != expected {
+		t.Errorf("Expected the compiled upsert to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestPostgresGrammar_CompileInsertUsing(t *testing.T) {
+	g := NewPostgresGrammar("")
+	q := &query.Query{From: "archived_users", Returning: "id"}
+	source := &query.Query{
+		Columns: []string{"id", "name"},
+		From:    "users",
+		Wheres:  []*query.Where{{Type: "Basic", Column: "active", Operator: "=", Value: false, Boolean: "and"}},
+	}
+
+	expected := `INSERT INTO "archived_users" ("id", "name") SELECT "id", "name" FROM "users" WHERE "active" = $1`
+	if sql := g.CompileInsertUsing(q, []string{"id", "name"}, source); sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+}
@@ -0,0 +1,58 @@
+package grammar
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]func(prefix string) Grammar)
+)
+
+func init() {
+	Register("mysql", func(prefix string) Grammar {
+		return NewMySqlGrammar(prefix)
+	})
+	Register("sqlite3", func(prefix string) Grammar {
+		return NewSQLiteGrammar(prefix)
+	})
+	Register("postgres", func(prefix string) Grammar {
+		return NewPostgresGrammar(prefix)
+	})
+	Register("pgx", func(prefix string) Grammar {
+		return NewPostgresGrammar(prefix)
+	})
+	Register("sqlserver", func(prefix string) Grammar {
+		return NewSqlServerGrammar(prefix)
+	})
+	Register("mssql", func(prefix string) Grammar {
+		return NewSqlServerGrammar(prefix)
+	})
+}
+
+// Register Make a query grammar available by the provided driver name.
+// Registering a name twice replaces the previous grammar.
+func Register(driverName string, factory func(prefix string) Grammar) {
+	if factory == nil {
+		panic("grammar: Register factory is nil")
+	}
+
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	factories[driverName] = factory
+}
+
+// New Create the query grammar registered by the driver name with the table prefix.
+func New(driverName string, prefix string) (Grammar, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[driverName]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Grammar [%s] not registered.", driverName)
+	}
+
+	return factory(prefix), nil
+}
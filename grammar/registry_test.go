@@ -0,0 +1,42 @@
+package grammar
+
+import (
+	"testing"
+)
+
+type tidbGrammar struct {
+	*MySqlGrammar
+}
+
+func TestRegister(t *testing.T) {
+	Register("tidb", func(prefix string) Grammar {
+		return &tidbGrammar{NewMySqlGrammar(prefix)}
+	})
+
+	g, err := New("tidb", "t1_")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tidb, ok := g.(*tidbGrammar)
+	if !ok {
+		t.Fatalf("Expected the registered grammar but instead got %T !", g)
+	}
+	if tidb.tablePrefix != "t1_" {
+		t.Errorf("Expected the table prefix to be %s but instead got %s !", "t1_", tidb.tablePrefix)
+	}
+}
+
+func TestNew(t *testing.T) {
+	g, err := New("sqlite3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.(*SQLiteGrammar); !ok {
+		t.Errorf("Expected the sqlite3 driver to use the SQLite grammar but instead got %T !", g)
+	}
+
+	if _, err := New("unknown", ""); err == nil {
+		t.Error("Expected an error for a driver without a registered grammar")
+	}
+}
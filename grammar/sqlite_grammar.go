@@ -0,0 +1,79 @@
+package grammar
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/thinkoner/torm/query"
+)
+
+type SQLiteGrammar struct {
+	BaseGrammar
+}
+
+// NewSQLiteGrammar Create a new SQLite query grammar.
+func NewSQLiteGrammar(tablePrefix string) *SQLiteGrammar {
+	g := &SQLiteGrammar{}
+	g.tablePrefix = tablePrefix
+	g.dialect = g
+	return g
+}
+
+// wrapUnion Wrap a union subquery in a derived table, as SQLite does not
+// allow parentheses around the selects of a compound select.
+func (g *SQLiteGrammar) wrapUnion(sql string) string {
+	return "select * from (" + sql + ")"
+}
+
+// compileLock Compile the lock into SQL, SQLite locks the whole database
+// during the transactions so the locks are ignored.
+func (g *SQLiteGrammar) compileLock(query *query.Query, lock *query.Lock) string {
+	return ""
+}
+
+// CompileInsert Compile an insert statement into SQL, SQLite has no DEFAULT
+// keyword to fill the missing columns with.
+func (g *SQLiteGrammar) CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	if strings.EqualFold(query.FillMissing, "DEFAULT") {
+		return "", nil, errors.New("SQLite does not support filling the missing columns with DEFAULT.")
+	}
+	return g.BaseGrammar.CompileInsert(query, values)
+}
+
+// CompileInsertOrIgnore Compile an insert ignore statement into SQL.
+func (g *SQLiteGrammar) CompileInsertOrIgnore(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	sql, bindings, err := g.CompileInsert(query, values)
+	return strings.Replace(sql, "INSERT", "INSERT OR IGNORE", 1), bindings, err
+}
+
+// CompileUpsert Compile an "upsert" statement into SQL.
+func (g *SQLiteGrammar) CompileUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error) {
+	return g.compileOnConflictUpsert(query, values, uniqueBy, update)
+}
+
+// CompileUpdate Compile an update statement into SQL.
+func (g *SQLiteGrammar) CompileUpdate(query *query.Query, values map[string]interface{}) string {
+	if len(query.Joins) == 0 && query.Limit == 0 {
+		return g.BaseGrammar.CompileUpdate(query, values)
+	}
+
+	// SQLite does not support joins or limits on updates, so we will select the
+	// row ids of the matching records and restrict the update to those rows.
+	return g.compileUpdateWithJoinsOrLimit(query, values, "rowid")
+}
+
+// PrepareBindingsForUpdate Prepare the bindings for an update statement.
+func (g *SQLiteGrammar) PrepareBindingsForUpdate(bindings map[string][]interface{}, values map[string]interface{}) []interface{} {
+	return g.prepareBindingsForUpdateWithJoinsOrLimit(bindings, values)
+}
+
+// CompileDelete Compile a delete statement into SQL.
+func (g *SQLiteGrammar) CompileDelete(query *query.Query) string {
+	if len(query.Joins) == 0 && query.Limit == 0 {
+		return g.BaseGrammar.CompileDelete(query)
+	}
+
+	// SQLite does not support "DELETE t FROM ... JOIN" nor limits on deletes, so
+	// we will delete the rows whose ids are selected by an equivalent query.
+	return g.compileDeleteWithJoinsOrLimit(query, "rowid")
+}
@@ -0,0 +1,140 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/thinkoner/torm/query"
+)
+
+func TestSQLiteGrammar_CompileSelect(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{
+		From: "users",
+		Wheres: []*query.Where{
+			{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"},
+			{Type: "Null", Column: "users.deleted_at", Boolean: "and"},
+		},
+		Limit: 1,
+	}
+
+	expected := `SELECT * FROM "users" WHERE "gender" = ? and "users"."deleted_at" IS NULL LIMIT 1 OFFSET 0`
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSQLiteGrammar_CompileUpdate(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+	}
+
+	expected := `UPDATE "users" SET "name" = ? WHERE "gender" = ?`
+	if sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Orders = []*query.Order{{Column: "id", Direction: "DESC"}}
+	q.Limit = 1
+
+	expected = `UPDATE "users" SET "name" = ? WHERE rowid IN (SELECT "users".rowid FROM "users" WHERE "gender" = ? ORDER BY "id" DESC LIMIT 1 OFFSET 0)`
+	if sql := g.CompileUpdate(q, map[string]interface{}{"users.name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+
+	bindings := g.PrepareBindingsForUpdate(
+		map[string][]interface{}{"where": {"M"}},
+		map[string]interface{}{"users.name": "Andrew"},
+	)
+	if len(bindings) != 2 || bindings[0] != "Andrew" || bindings[1] != "M" {
+		t.Errorf("Expected the bindings to be [Andrew M] but instead got %v !", bindings)
+	}
+}
+
+func TestSQLiteGrammar_CompileDelete(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{
+		From: "users",
+		Joins: []*query.Join{{
+			Type:  "INNER",
+			Table: "contacts",
+			Query: &query.Query{
+				JoinClause: true,
+				Wheres:     []*query.Where{{Type: "Column", First: "users.id", Operator: "=", Second: "contacts.user_id", Boolean: "and"}},
+			},
+		}},
+		Wheres: []*query.Where{{Type: "Basic", Column: "contacts.phone", Operator: "=", Value: "555", Boolean: "and"}},
+	}
+
+	expected := `DELETE FROM "users" WHERE rowid IN (SELECT "users".rowid FROM "users" INNER JOIN "contacts" ON "users"."id" = "contacts"."user_id" WHERE "contacts"."phone" = ?)`
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+
+	q = &query.Query{From: "users"}
+	expected = `DELETE FROM "users"`
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSQLiteGrammar_CompileInsertOrIgnore(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{From: "users"}
+
+	sql, _, err := g.CompileInsertOrIgnore(q, []map[string]interface{}{{"name": "Andrew"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `INSERT OR IGNORE INTO "users" ("name") values (?)`
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSQLiteGrammar_CompileLock(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{From: "jobs", Lock: &query.Lock{Type: "update"}}
+
+	expected := `SELECT * FROM "jobs"`
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSQLiteGrammar_CompileUpsert(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{From: "products"}
+
+	sql, _, err := g.CompileUpsert(q, []map[string]interface{}{{"sku": "a"}}, []string{"sku"}, []string{"sku"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `INSERT INTO "products" ("sku") values (?) ON CONFLICT ("sku") DO UPDATE SET "sku" = excluded."sku"`
+	if sql != expected {
+		t.Errorf("Expected the compiled upsert to be %s but instead got %s !", expected, sql)
+	}
+
+	if _, _, err = g.CompileUpsert(q, []map[string]interface{}{{"sku": "a"}}, nil, []string{"sku"}); err == nil {
+		t.Error("Expect: an upsert without unique columns should fail")
+	}
+}
+
+func TestSQLiteGrammar_CompileInsert(t *testing.T) {
+	g := NewSQLiteGrammar("")
+	q := &query.Query{From: "users", FillMissing: "DEFAULT"}
+	values := []map[string]interface{}{{"name": "Andrew", "gender": "M"}, {"name": "Boston"}}
+
+	if _, _, err := g.CompileInsert(q, values); err == nil {
+		t.Error("Expect: filling the missing columns with DEFAULT should fail")
+	}
+
+	q.FillMissing = "NULL"
+	expected := `INSERT INTO "users" ("gender", "name") values (?, ?),(NULL, ?)`
+	if sql, _, _ := g.CompileInsert(q, values); sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+}
@@ -0,0 +1,302 @@
+package grammar
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/thinkoner/torm/query"
+)
+
+type SqlServerGrammar struct {
+	BaseGrammar
+}
+
+// NewSqlServerGrammar Create a new SQL Server query grammar.
+func NewSqlServerGrammar(tablePrefix string) *SqlServerGrammar {
+	g := &SqlServerGrammar{}
+	g.tablePrefix = tablePrefix
+	g.dialect = g
+	return g
+}
+
+// wrapValue Wrap a single string in keyword identifiers.
+func (g *SqlServerGrammar) wrapValue(value string) string {
+	if value != "*" {
+		return "[" + strings.Replace(value, "]", "]]", -1) + "]"
+	}
+	return value
+}
+
+// CompileSelect Compile a select query into SQL.
+func (g *SqlServerGrammar) CompileSelect(query *query.Query) string {
+	return g.numberParameters(g.BaseGrammar.CompileSelect(query))
+}
+
+// compileColumns Compile the "select *" portion of the query.
+func (g *SqlServerGrammar) compileColumns(query *query.Query, columns []string) string {
+	sql := g.BaseGrammar.compileColumns(query, columns)
+
+	// A limit without an offset is compiled as a "top" clause, offsets need the
+	// "offset fetch" clause compiled after the orders of the query. The "top"
+	// clause follows the "distinct" keyword.
+	if len(sql) > 0 && g.usesTop(query) {
+		sel := "SELECT "
+		if query.Distinct {
+			sel = "SELECT DISTINCT "
+		}
+		sql = strings.Replace(sql, sel, fmt.Sprintf("%sTOP %d ", sel, query.Limit), 1)
+	}
+	return sql
+}
+
+// compileLimit Compile the "limit" portions of the query.
+func (g *SqlServerGrammar) compileLimit(query *query.Query, limit uint64) string {
+	return ""
+}
+
+// compileOffset Compile the "offset" portions of the query.
+func (g *SqlServerGrammar) compileOffset(query *query.Query, offset uint64) string {
+	if offset == 0 && (query.Limit == 0 || g.usesTop(query)) {
+		return ""
+	}
+
+	// SQL Server only allows the "offset fetch" clause after an "order by" one,
+	// so we will order by a constant when the query has no orders.
+	sql := fmt.Sprintf("OFFSET %d ROWS", offset)
+	if len(query.Orders) == 0 {
+		sql = "ORDER BY (SELECT 0) " + sql
+	}
+	if query.Limit > 0 {
+		sql = sql + fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", query.Limit)
+	}
+	return sql
+}
+
+// usesTop Determine if the limit of the query is compiled as a "top" clause,
+// the pagination of the unions has no columns and always uses "offset fetch".
+func (g *SqlServerGrammar) usesTop(query *query.Query) bool {
+	return len(query.Columns) > 0 && query.Aggregate == nil && query.Limit > 0 && query.Offset == 0
+}
+
+// wrapUnion Wrap a union subquery in a derived table.
+func (g *SqlServerGrammar) wrapUnion(sql string) string {
+	return "select * from (" + sql + ") as " + g.WrapTable("temp_table")
+}
+
+// compileFrom Compile the "from" portion of the query, the locks are compiled
+// as table hints.
+func (g *SqlServerGrammar) compileFrom(query *query.Query, table string) string {
+	from := g.BaseGrammar.compileFrom(query, table)
+
+	if query.Lock == nil {
+		return from
+	}
+
+	var hints []string
+	switch query.Lock.Type {
+	case "update":
+		hints = []string{"rowlock", "updlock", "holdlock"}
+	case "share":
+		hints = []string{"rowlock", "holdlock"}
+	default:
+		return from + " " + query.Lock.Sql
+	}
+
+	// The rows locked by other transactions are skipped with the "readpast"
+	// hint, which is not allowed with the serializable "holdlock" one.
+	switch query.Lock.Option {
+	case "NOWAIT":
+		hints = append(hints, "nowait")
+	case "SKIP LOCKED":
+		hints = append(hints[:len(hints)-1], "readpast")
+	}
+
+	return from + " with(" + strings.Join(hints, ",") + ")"
+}
+
+// compileLock Compile the lock into SQL, the locks are table hints of the
+// "from" portion of the query.
+func (g *SqlServerGrammar) compileLock(query *query.Query, lock *query.Lock) string {
+	return ""
+}
+
+// CompileInsert Compile an insert statement into SQL.
+func (g *SqlServerGrammar) CompileInsert(query *query.Query, values []map[string]interface{}) (string, []interface{}, error) {
+	columns, rows, bindings, err := g.compileInsertValues(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sql := fmt.Sprintf(
+		"INSERT INTO %s (%s)%s values %s",
+		g.WrapTable(query.From),
+		g.wrapColumns(columns),
+		g.compileOutput(query),
+		strings.Join(rows, ","),
+	)
+	return g.numberParameters(sql), bindings, nil
+}
+
+// compileOutput Compile the clause returning the id of the inserted rows,
+// which is empty when the query returns no column.
+func (g *SqlServerGrammar) compileOutput(query *query.Query) string {
+	if query.Returning == "" {
+		return ""
+	}
+	return " OUTPUT INSERTED." + g.wrapValue(query.Returning)
+}
+
+// CompileInsertUsing Compile an insert statement using a subquery into SQL.
+func (g *SqlServerGrammar) CompileInsertUsing(query *query.Query, columns []string, source *query.Query) string {
+	return g.numberParameters(g.BaseGrammar.CompileInsertUsing(query, columns, source))
+}
+
+// CompileUpsert Compile an "upsert" statement into SQL, as a "merge" statement
+// using the values as its source.
+func (g *SqlServerGrammar) CompileUpsert(query *query.Query, values []map[string]interface{}, uniqueBy []string, update []string) (string, []interface{}, error) {
+	if len(uniqueBy) == 0 {
+		return "", nil, errors.New("Upsert requires at least one unique column.")
+	}
+
+	columns, rows, bindings, err := g.compileInsertValues(query, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	table := g.WrapTable(query.From)
+	source := g.wrapValue("torm_source")
+
+	var on []string
+	for _, column := range uniqueBy {
+		on = append(on, source+"."+g.wrapValue(column)+" = "+table+"."+g.wrapValue(column))
+	}
+
+	var sets []string
+	for _, column := range update {
+		sets = append(sets, g.wrapValue(column)+" = "+source+"."+g.wrapValue(column))
+	}
+
+	var inserted []string
+	for _, column := range columns {
+		inserted = append(inserted, source+"."+g.wrapValue(column))
+	}
+
+	sql := fmt.Sprintf(
+		"MERGE %s USING (VALUES %s) %s (%s) ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);",
+		table,
+		strings.Join(rows, ", "),
+		source,
+		g.wrapColumns(columns),
+		strings.Join(on, " AND "),
+		strings.Join(sets, ", "),
+		g.wrapColumns(columns),
+		strings.Join(inserted, ", "),
+	)
+
+	return g.numberParameters(sql), bindings, nil
+}
+
+// CompileUpdate Compile an update statement into SQL.
+func (g *SqlServerGrammar) CompileUpdate(query *query.Query, values map[string]interface{}) string {
+	table := g.WrapTable(query.From)
+	columns := g.compileUpdateColumns(values)
+	where := g.compileWheres(query)
+
+	top := ""
+	if query.Limit > 0 {
+		top = fmt.Sprintf(" TOP (%d)", query.Limit)
+	}
+
+	if len(query.Joins) > 0 {
+		joins := g.compileJoins(query, query.Joins)
+		return g.numberParameters(strings.TrimSpace(fmt.Sprintf(
+			"UPDATE%s %s SET %s FROM %s %s %s", top, tableAlias(table), columns, table, joins, where,
+		)))
+	}
+
+	return g.numberParameters(strings.TrimSpace(fmt.Sprintf("UPDATE%s %s SET %s %s", top, table, columns, where)))
+}
+
+// PrepareBindingsForUpdate Prepare the bindings for an update statement.
+func (g *SqlServerGrammar) PrepareBindingsForUpdate(bindings map[string][]interface{}, values map[string]interface{}) []interface{} {
+	return g.prepareBindingsForUpdateWithJoinsOrLimit(bindings, values)
+}
+
+// CompileDelete Compile a delete statement into SQL.
+func (g *SqlServerGrammar) CompileDelete(query *query.Query) string {
+	sql := g.BaseGrammar.CompileDelete(query)
+
+	if query.Limit > 0 && len(query.Joins) == 0 {
+		sql = strings.Replace(sql, "DELETE ", fmt.Sprintf("DELETE TOP (%d) ", query.Limit), 1)
+	}
+	return g.numberParameters(sql)
+}
+
+// CompileSavepoint Compile the SQL statement to define a savepoint.
+func (g *SqlServerGrammar) CompileSavepoint(name string) string {
+	return "SAVE TRANSACTION " + name
+}
+
+// CompileSavepointRollBack Compile the SQL statement to execute a savepoint rollback.
+func (g *SqlServerGrammar) CompileSavepointRollBack(name string) string {
+	return "ROLLBACK TRANSACTION " + name
+}
+
+// CompileSavepointRelease Compile the SQL statement to release a savepoint,
+// SQL Server releases the savepoints with the transaction.
+func (g *SqlServerGrammar) CompileSavepointRelease(name string) string {
+	return ""
+}
+
+// ReturnsInsertId Determine if insert statements return the inserted id as a
+// result set, of the Returning column of the query, instead of reporting it
+// through the driver.
+func (g *SqlServerGrammar) ReturnsInsertId() bool {
+	return true
+}
+
+// SubstituteBindingsIntoRawSql Substitute the given bindings into the given
+// raw SQL query, for debugging purposes only.
+func (g *SqlServerGrammar) SubstituteBindingsIntoRawSql(sql string, bindings []interface{}) string {
+	return g.substituteBindings(sql, bindings, "@p")
+}
+
+// escapeString Quote a string literal for the raw SQL.
+func (g *SqlServerGrammar) escapeString(value string) string {
+	return "N'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// escapeBinary Quote a binary literal for the raw SQL.
+func (g *SqlServerGrammar) escapeBinary(value []byte) string {
+	return "0x" + hex.EncodeToString(value)
+}
+
+// escapeBool Quote a boolean literal for the raw SQL.
+func (g *SqlServerGrammar) escapeBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// MaxParameters Get the maximum number of place-holders of a statement, SQL
+// Server allows 2100 parameters to a request.
+func (g *SqlServerGrammar) MaxParameters() int {
+	return 2100
+}
+
+// MaxInsertRows Get the maximum number of rows of an insert statement, SQL
+// Server allows 1000 rows in a "values" clause.
+func (g *SqlServerGrammar) MaxInsertRows() int {
+	return 1000
+}
+
+// numberParameters Replace the "?" place-holders with "@p1", "@p2" ... "@pn".
+func (g *SqlServerGrammar) numberParameters(sql string) string {
+	return numberParameters(sql, func(n int) string {
+		return "@p" + strconv.Itoa(n)
+	})
+}
@@ -0,0 +1,210 @@
+package grammar
+
+import (
+	"testing"
+
+	"github.com/thinkoner/torm/query"
+)
+
+func TestSqlServerGrammar_CompileSelect(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "users.gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Limit:  10,
+	}
+
+	expected := "SELECT TOP 10 * FROM [users] WHERE [users].[gender] = @p1"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Distinct = true
+	expected = "SELECT DISTINCT TOP 10 * FROM [users] WHERE [users].[gender] = @p1"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Distinct = false
+	q.Offset = 20
+	expected = "SELECT * FROM [users] WHERE [users].[gender] = @p1 ORDER BY (SELECT 0) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Orders = []*query.Order{{Column: "name", Direction: "ASC"}}
+	expected = "SELECT * FROM [users] WHERE [users].[gender] = @p1 ORDER BY [name] ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Limit = 0
+	expected = "SELECT * FROM [users] WHERE [users].[gender] = @p1 ORDER BY [name] ASC OFFSET 20 ROWS"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileInsert(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{From: "users", Returning: "id"}
+
+	sql, _, _ := g.CompileInsert(q, []map[string]interface{}{{"name": "Andrew"}, {"name": "Boston"}})
+
+	expected := "INSERT INTO [users] ([name]) OUTPUT INSERTED.[id] values (@p1),(@p2)"
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+
+	sql, _, _ = g.CompileInsert(&query.Query{From: "users"}, []map[string]interface{}{{"name": "Andrew"}})
+
+	expected = "INSERT INTO [users] ([name]) values (@p1)"
+	if sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+
+	if _, _, err := g.CompileInsertOrIgnore(q, []map[string]interface{}{{"name": "Andrew"}}); err == nil {
+		t.Error("Expected the sql server grammar not to support insert ignore")
+	}
+
+	if rows := g.MaxInsertRows(); rows != 1000 {
+		t.Errorf("Expected the inserts to be limited to 1000 rows but instead got %d !", rows)
+	}
+}
+
+func TestSqlServerGrammar_CompileUpdate(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Limit:  5,
+	}
+
+	expected := "UPDATE TOP (5) [users] SET [name] = @p1 WHERE [gender] = @p2"
+	if sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+
+	q.Limit = 0
+	q.Joins = []*query.Join{{
+		Type:  "INNER",
+		Table: "contacts",
+		Query: &query.Query{
+			JoinClause: true,
+			Wheres:     []*query.Where{{Type: "Column", First: "users.id", Operator: "=", Second: "contacts.user_id", Boolean: "and"}},
+		},
+	}}
+
+	expected = "UPDATE [users] SET [name] = @p1 FROM [users] INNER JOIN [contacts] ON [users].[id] = [contacts].[user_id] WHERE [gender] = @p2"
+	if sql := g.CompileUpdate(q, map[string]interface{}{"name": "Andrew"}); sql != expected {
+		t.Errorf("Expected the compiled update to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileDelete(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{
+		From:   "users",
+		Wheres: []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Limit:  5,
+	}
+
+	expected := "DELETE TOP (5) FROM [users] WHERE [gender] = @p1"
+	if sql := g.CompileDelete(q); sql != expected {
+		t.Errorf("Expected the compiled delete to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileSavepoint(t *testing.T) {
+	g := NewSqlServerGrammar("")
+
+	if sql := g.CompileSavepoint("trans2"); sql != "SAVE TRANSACTION trans2" {
+		t.Errorf("Expected the compiled savepoint to be %s but instead got %s !", "SAVE TRANSACTION trans2", sql)
+	}
+	if sql := g.CompileSavepointRelease("trans2"); sql != "" {
+		t.Errorf("Expected the compiled release to be empty but instead got %s !", sql)
+	}
+}
+
+func TestSqlServerGrammar_SubstituteBindingsIntoRawSql(t *testing.T) {
+	g := NewSqlServerGrammar("")
+
+	sql := g.SubstituteBindingsIntoRawSql(
+		"SELECT * FROM [users] WHERE [name] = @p1 and [avatar] = @p2 and [active] = @p3",
+		[]interface{}{"O'Brien", []byte{0x01}, false},
+	)
+
+	expected := "SELECT * FROM [users] WHERE [name] = N'O''Brien' and [avatar] = 0x01 and [active] = 0"
+	if sql != expected {
+		t.Errorf("Expected the raw sql to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileUnion(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{
+		From:       "users",
+		Wheres:     []*query.Where{{Type: "Basic", Column: "gender", Operator: "=", Value: "M", Boolean: "and"}},
+		Unions:     []*query.Union{{Query: &query.Query{From: "admins", Limit: 5}}},
+		UnionLimit: 10,
+	}
+
+	expected := "select * from (SELECT * FROM [users] WHERE [gender] = @p1) as [temp_table] union select * from (SELECT TOP 5 * FROM [admins]) as [temp_table] ORDER BY (SELECT 0) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the compiled union to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileLock(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{From: "jobs", Limit: 1, Lock: &query.Lock{Type: "update", Option: "SKIP LOCKED"}}
+
+	expected := "SELECT TOP 1 * FROM [jobs] with(rowlock,updlock,readpast)"
+	if sql := g.CompileSelect(q); sql != expected {
+		t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestSqlServerGrammar_CompileUpsert(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{From: "products"}
+
+	sql, bindings, err := g.CompileUpsert(
+		q,
+		[]map[string]interface{}{{"sku": "a", "price": 1}, {"sku": "b", "price": 2}},
+		[]string{"sku"},
+		[]string{"price"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "MERGE [products] USING (VALUES (@p1, @p2), (@p3, @p4)) [torm_source] ([price], [sku]) ON [torm_source].[sku] = [products].[sku] " +
+		"WHEN MATCHED THEN UPDATE SET [price] = [torm_source].[price] " +
+		"WHEN NOT MATCHED THEN INSERT ([price], [sku]) VALUES ([torm_source].[price], [torm_source].[sku]);"
+	if sql != expected {
+		t.Errorf("Expected the compiled upsert to be %s but instead got %s !", expected, sql)
+	}
+	if len(bindings) != 4 || bindings[0] != 1 || bindings[1] != "a" {
+		t.Errorf("Expected the bindings in the column order but instead got %v !", bindings)
+	}
+
+	if _, _, err = g.CompileUpsert(q, []map[string]interface{}{{"sku": "a"}}, nil, []string{"sku"}); err == nil {
+		t.Error("Expect: an upsert without unique columns should fail")
+	}
+}
+
+func TestSqlServerGrammar_CompileInsertUsing(t *testing.T) {
+	g := NewSqlServerGrammar("")
+	q := &query.Query{From: "archived_users", Returning: "id"}
+	source := &query.Query{
+		Columns: []string{"id"},
+		From:    "users",
+		Wheres:  []*query.Where{{Type: "Basic", Column: "active", Operator: "=", Value: false, Boolean: "and"}},
+	}
+
+	expected := "INSERT INTO [archived_users] ([id]) SELECT [id] FROM [users] WHERE [active] = @p1"
+	if sql := g.CompileInsertUsing(q, []string{"id"}, source); sql != expected {
+		t.Errorf("Expected the compiled insert to be %s but instead got %s !", expected, sql)
+	}
+}
@@ -0,0 +1,128 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"time"
+)
+
+// LogLevel The level at which an executed query is logged.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// String Get the name of the log level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ExecutedQuery An SQL statement executed by a connection.
+type ExecutedQuery struct {
+	SQL      string
+	Bindings []interface{}
+	Duration time.Duration
+	// RowsAffected The rows affected by the statement, or fetched by a select.
+	RowsAffected int64
+	Err          error
+}
+
+// Logger Logs the queries executed by a connection.
+type Logger interface {
+	LogQuery(ctx context.Context, level LogLevel, query ExecutedQuery)
+}
+
+type nopLogger struct{}
+
+// NewNopLogger Create a logger discarding every query.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+// LogQuery Discard the query.
+func (nopLogger) LogQuery(ctx context.Context, level LogLevel, query ExecutedQuery) {}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// NewStdLogger Create a logger writing the queries logged at the level or above
+// to a standard library logger, the default logger is used when it is nil.
+func NewStdLogger(logger *log.Logger, level LogLevel) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &stdLogger{logger: logger, level: level}
+}
+
+// LogQuery Write the query to the standard library logger.
+func (l *stdLogger) LogQuery(ctx context.Context, level LogLevel, query ExecutedQuery) {
+	if level < l.level {
+		return
+	}
+
+	msg := fmt.Sprintf("[%s] [%s] [rows:%d] %s %v", level, query.Duration, query.RowsAffected, query.SQL, query.Bindings)
+	if query.Err != nil {
+		msg += " error: " + query.Err.Error()
+	}
+
+	l.logger.Println(msg)
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger Create a logger writing the queries to a structured logger,
+// the default logger is used when it is nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+// LogQuery Write the query to the structured logger.
+func (l *slogLogger) LogQuery(ctx context.Context, level LogLevel, query ExecutedQuery) {
+	attrs := []slog.Attr{
+		slog.String("sql", query.SQL),
+		slog.Any("bindings", query.Bindings),
+		slog.Duration("duration", query.Duration),
+		slog.Int64("rows", query.RowsAffected),
+	}
+	if query.Err != nil {
+		attrs = append(attrs, slog.Any("error", query.Err))
+	}
+
+	l.logger.LogAttrs(ctx, slogLevel(level), "query", attrs...)
+}
+
+// slogLevel Get the structured logging level of a log level.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
@@ -3,27 +3,124 @@ package torm
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 )
 
 type Config struct {
 	Driver string
 	Prefix string
 	Dsn    string
+	// Read The DSNs of the read replicas, one of them is picked at random for
+	// the select statements.
+	Read []string
+	// Write The DSNs of the primary databases, one of them is picked at random
+	// instead of the Dsn.
+	Write []string
+	// Sticky Send the selects of a connection Session to the write database
+	// once the session has written to it, to read its own writes.
+	Sticky bool
+	// Grammar The name of a registered query grammar, defaults to the Driver and
+	// to the MySQL grammar when no grammar is registered for the Driver.
+	Grammar string
+
+	// MaxOpenConns The maximum number of open connections of each database pool,
+	// zero means unlimited.
+	MaxOpenConns int
+	// MaxIdleConns The maximum number of idle connections of each database pool,
+	// zero keeps the database/sql default and a negative value disables them.
+	MaxIdleConns int
+	// ConnMaxLifetime The maximum amount of time a connection may be reused.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime The maximum amount of time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+
+	// Ping Verify the databases are reachable when the connection is opened.
+	Ping bool
+	// PingRetries The number of times a failed ping is retried.
+	PingRetries int
+	// PingBackoff The delay before the first retry, doubled after each retry.
+	PingBackoff time.Duration
+
+	// Logger The logger of the executed queries, they are not logged when nil.
+	Logger Logger
+	// SlowThreshold The duration from which the queries are logged as warnings.
+	SlowThreshold time.Duration
 }
 
 // Database manager.
 type Manager struct {
+	mu          sync.Mutex
 	configs     map[string]Config
-	connections map[string]Connection
+	connections map[string]*Connection
+	defaultName string
+}
+
+// NewManager Create a new database manager for the configured connections.
+func NewManager(configs map[string]Config, defaultName string) *Manager {
+	return &Manager{
+		configs:     configs,
+		connections: make(map[string]*Connection),
+		defaultName: defaultName,
+	}
+}
+
+// Connection Get the default database connection instance.
+func (m *Manager) Connection() (*Connection, error) {
+	return m.Connect(m.defaultName)
 }
 
 // Connect Get a database connection instance.
 func (m *Manager) Connect(name string) (*Connection, error) {
-	if conn, ok := m.connections[name]; ok {
-		return &conn, nil
+	m.mu.Lock()
+	conn, ok := m.connections[name]
+	m.mu.Unlock()
+
+	if ok {
+		return conn, nil
+	}
+
+	// The connection is opened without holding the lock, since opening it may
+	// ping the database, so another goroutine may have cached a connection in
+	// the meantime, in which case it wins and ours is closed.
+	conn, err := m.make(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cached, ok := m.connections[name]; ok {
+		conn.Close()
+		return cached, nil
+	}
+	m.connections[name] = conn
+
+	return conn, nil
+}
+
+// Purge Disconnect from the given database and remove from local cache.
+func (m *Manager) Purge(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conn, ok := m.connections[name]
+	if !ok {
+		return nil
+	}
+	delete(m.connections, name)
+
+	return conn.Close()
+}
+
+// Reconnect Reconnect to the given database.
+func (m *Manager) Reconnect(name string) (*Connection, error) {
+	if err := m.Purge(name); err != nil {
+		return nil, err
 	}
 
-	return m.make(name)
+	return m.Connect(name)
 }
 
 func (m *Manager) make(name string) (*Connection, error) {
@@ -0,0 +1,171 @@
+package torm
+
+import (
+	"sync"
+	"testing"
+)
+
+func newTestManager() *Manager {
+	return NewManager(map[string]Config{
+		"sqlite": {
+			Driver: "sqlite3",
+			Dsn:    "file:manager_write?mode=memory&cache=shared",
+			Read:   []string{"file:manager_read?mode=memory&cache=shared"},
+		},
+		"sticky": {
+			Driver: "sqlite3",
+			Dsn:    "file:manager_write?mode=memory&cache=shared",
+			Read:   []string{"file:manager_read?mode=memory&cache=shared"},
+			Sticky: true,
+		},
+	}, "sqlite")
+}
+
+func TestManager_Connect(t *testing.T) {
+	m := newTestManager()
+
+	conn, err := m.Connection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := m.Connect("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conn != same {
+		t.Error("Expect: the connection should be cached")
+	}
+
+	if _, err = m.Connect("missing"); err == nil {
+		t.Error("Expect: connecting to a missing database should fail")
+	}
+}
+
+func TestManager_ConnectConcurrently(t *testing.T) {
+	m := newTestManager()
+
+	conns := make([]*Connection, 10)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], _ = m.Connect("sqlite")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, conn := range conns {
+		if conn == nil || conn != conns[0] {
+			t.Fatal("Expect: the concurrent connects should get the same connection")
+		}
+	}
+
+	if err := conns[0].DB.Ping(); err != nil {
+		t.Errorf("Expect: the cached connection should be open, got %s", err)
+	}
+}
+
+func TestManager_Reconnect(t *testing.T) {
+	m := newTestManager()
+
+	conn, err := m.Connection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := m.Reconnect("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conn == fresh {
+		t.Error("Expect: reconnecting should open a new connection")
+	}
+
+	if err = conn.DB.Ping(); err == nil {
+		t.Error("Expect: the purged connection should be closed")
+	}
+}
+
+func TestManager_ReadWrite(t *testing.T) {
+	m := newTestManager()
+
+	conn, err := m.Connection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Statement(`CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, name TEXT)`)
+	conn.Statement(`DELETE FROM users`)
+	conn.readDB.Exec(`CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, name TEXT)`)
+	conn.readDB.Exec(`DELETE FROM users`)
+
+	_, _, err = conn.Table("users").Insert(map[string]interface{}{"name": "Andrew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	if err = conn.Table("users").Count(&count); err != nil {
+		t.Error(err)
+	}
+	if count != 0 {
+		t.Error("Expect: the select should be sent to the read database")
+	}
+
+	err = conn.Transaction(func(tx *Connection) error {
+		return tx.Table("users").Count(&count)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if count != 1 {
+		t.Error("Expect: the select in a transaction should be sent to the write database")
+	}
+}
+
+func TestManager_Sticky(t *testing.T) {
+	m := newTestManager()
+
+	conn, err := m.Connect("sticky")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Statement(`CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, name TEXT)`)
+	conn.Statement(`DELETE FROM users`)
+	conn.readDB.Exec(`CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, name TEXT)`)
+	conn.readDB.Exec(`DELETE FROM users`)
+
+	session := conn.Session()
+
+	var count int64
+	if err = session.Table("users").Count(&count); err != nil {
+		t.Error(err)
+	}
+	if count != 0 {
+		t.Error("Expect: the select before a write should be sent to the read database")
+	}
+
+	_, _, err = session.Table("users").Insert(map[string]interface{}{"name": "Andrew"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = session.Table("users").Count(&count); err != nil {
+		t.Error(err)
+	}
+	if count != 1 {
+		t.Error("Expect: the select after a write should be sent to the write database")
+	}
+
+	if err = conn.Session().Table("users").Count(&count); err != nil {
+		t.Error(err)
+	}
+	if count != 0 {
+		t.Error("Expect: a new session should read from the read database")
+	}
+}
@@ -4,6 +4,7 @@ type Query struct {
 	Distinct    bool
 	Columns     []string
 	From        string
+	FromQuery   *Query
 	Joins       []*Join
 	Wheres      []*Where
 	Groups      []string
@@ -16,7 +17,14 @@ type Query struct {
 	UnionLimit  uint64
 	UnionOffset uint64
 	Aggregate   *Aggregate
+	Lock        *Lock
 	JoinClause  bool
+	// FillMissing The keyword inserted in place of the columns missing from some
+	// of the inserted rows, "DEFAULT" or "NULL", they are an error when empty.
+	FillMissing string
+	// Returning The column returned as the inserted id by the inserts of the
+	// grammars which can't report it through the driver, none when empty.
+	Returning string
 }
 
 type Aggregate struct {
@@ -41,6 +49,7 @@ type Where struct {
 	Values   []interface{}
 	Boolean  string
 	Not      bool
+	Query    *Query
 }
 
 type Having struct {
@@ -59,7 +68,17 @@ type Order struct {
 	Direction string
 }
 
+// Lock The pessimistic lock of a select, Type is "update", "share" or "raw"
+// and Option is empty, "NOWAIT" or "SKIP LOCKED".
+type Lock struct {
+	Type   string
+	Sql    string
+	Option string
+}
+
 type Union struct {
+	Query *Query
+	All   bool
 }
 
 type UnionOrder struct {
@@ -0,0 +1,69 @@
+package torm
+
+import "sync"
+
+// queryLog Records the queries executed by a connection in memory, it is
+// shared by the copies of the connection.
+type queryLog struct {
+	mu      sync.Mutex
+	enabled bool
+	queries []ExecutedQuery
+}
+
+// record Add the query to the log when it is enabled.
+func (l *queryLog) record(query ExecutedQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.enabled {
+		l.queries = append(l.queries, query)
+	}
+}
+
+// EnableQueryLog Enable the query log on the connection.
+func (c *Connection) EnableQueryLog() {
+	log := c.getQueryLog()
+	log.mu.Lock()
+	log.enabled = true
+	log.mu.Unlock()
+}
+
+// DisableQueryLog Disable the query log on the connection.
+func (c *Connection) DisableQueryLog() {
+	log := c.getQueryLog()
+	log.mu.Lock()
+	log.enabled = false
+	log.mu.Unlock()
+}
+
+// LoggingQueries Determine whether we're logging queries.
+func (c *Connection) LoggingQueries() bool {
+	log := c.getQueryLog()
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return log.enabled
+}
+
+// GetQueryLog Get the connection query log.
+func (c *Connection) GetQueryLog() []ExecutedQuery {
+	log := c.getQueryLog()
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]ExecutedQuery(nil), log.queries...)
+}
+
+// FlushQueryLog Clear the query log.
+func (c *Connection) FlushQueryLog() {
+	log := c.getQueryLog()
+	log.mu.Lock()
+	log.queries = nil
+	log.mu.Unlock()
+}
+
+// getQueryLog Get the query log of the connection.
+func (c *Connection) getQueryLog() *queryLog {
+	if c.queryLog == nil {
+		c.queryLog = &queryLog{}
+	}
+	return c.queryLog
+}
@@ -23,6 +23,14 @@ func (s *Schema) SetId(id int64) error {
 	return nil
 }
 
+// PrimaryName Get the column of the primary key, empty when there is none.
+func (s *Schema) PrimaryName() string {
+	if s.PrimaryField == nil {
+		return ""
+	}
+	return s.PrimaryField.Name
+}
+
 func (s *Schema) Attributes() map[string]interface{} {
 	if s.attributes == nil {
 		s.attributes = make(map[string]interface{})
@@ -36,6 +44,20 @@ func (s *Schema) Attributes() map[string]interface{} {
 	return s.attributes
 }
 
+// Values Get the column values of the exported fields which are not ignored,
+// skipping the blank fields when omitBlank is true.
+func (s *Schema) Values(omitBlank bool) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, field := range s.Fields {
+		if field.Ignored || field.StructField.PkgPath != "" || (omitBlank && field.IsBlank) {
+			continue
+		}
+		values[field.Name] = field.Value.Interface()
+	}
+
+	return values
+}
+
 func NewSchema(model interface{}) (*Schema, error) {
 	results := reflect.Indirect(reflect.ValueOf(model))
 
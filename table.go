@@ -40,8 +40,7 @@ func (c *Connection) Create(model interface{}) error {
 		return err
 	}
 
-	attributes := schema.Attributes()
-	insertId, _, err := c.Model(model).Insert(attributes)
+	insertId, _, err := c.Model(model).Returning(schema.PrimaryName()).Insert(insertAttributes(schema))
 
 	if err != nil {
 		return err
@@ -67,7 +66,7 @@ func (c *Connection) Save(model interface{}) error {
 		_, err = c.Model(model).Where(schema.PrimaryField.Name, schema.PrimaryField.Value.Addr().Interface()).Update(attributes)
 	} else {
 		var insertId int64
-		insertId, _, err = c.Model(model).Insert(attributes)
+		insertId, _, err = c.Model(model).Returning(schema.PrimaryName()).Insert(insertAttributes(schema))
 		if err == nil {
 			schema.SetId(insertId)
 		}
@@ -93,3 +92,18 @@ func (c *Connection) Destroy(model interface{}) error {
 
 	return err
 }
+
+// insertAttributes Get the attributes of a new model, without its blank primary
+// key so the database generates it.
+func insertAttributes(schema *Schema) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	for name, value := range schema.Attributes() {
+		attributes[name] = value
+	}
+
+	if schema.PrimaryField != nil && schema.PrimaryField.IsBlank {
+		delete(attributes, schema.PrimaryField.Name)
+	}
+
+	return attributes
+}
@@ -1,10 +1,14 @@
 package torm
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/thinkoner/torm/grammar"
 )
 
 func TestTableSelectBasic(t *testing.T) {
@@ -172,3 +176,383 @@ func TestModelCreate(t *testing.T) {
 
 	t.Log(u.Id)
 }
+
+func TestTableWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var users []User
+	err := DB.Table("users").WithContext(ctx).Get(&users)
+	if err != context.Canceled {
+		t.Errorf("Expect: the query should be canceled, got %v", err)
+	}
+
+	_, err = DB.Table("users").WithContext(ctx).Where("name", "Nobody").Delete()
+	if err != context.Canceled {
+		t.Errorf("Expect: the delete should be canceled, got %v", err)
+	}
+
+	err = DB.WithContext(ctx).Create(&User{Name: "Canceled"})
+	if err != context.Canceled {
+		t.Errorf("Expect: the create should be canceled, got %v", err)
+	}
+}
+
+func TestTableToRawSql(t *testing.T) {
+	sql := NewBuilder(nil, grammar.NewMySqlGrammar("")).
+		From("users").
+		Where("name", "O'Brien").
+		Where("avatar", []byte("ab")).
+		ToRawSql()
+
+	expected := "SELECT * FROM `users` WHERE `name` = 'O\\'Brien' and `avatar` = X'6162'"
+	if sql != expected {
+		t.Errorf("Expected the raw sql to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestTableBindingsOrder(t *testing.T) {
+	expected := []interface{}{1, 2, 3, 4}
+
+	for i := 0; i < 100; i++ {
+		bindings := NewBuilder(nil, grammar.NewMySqlGrammar("")).
+			From("users").
+			OrderByRaw("field(id, ?)", 4).
+			Having("total", ">", 3).
+			Where("gender", 2).
+			SelectRaw("count(*) > ? as total", 1).
+			GetBindings()
+
+		if fmt.Sprint(bindings) != fmt.Sprint(expected) {
+			t.Fatalf("Expected the bindings to be %v but instead got %v !", expected, bindings)
+		}
+	}
+}
+
+func TestTableUnion(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, gender TEXT)")
+	conn.Insert("INSERT INTO users (name, gender) VALUES (?, ?), (?, ?), (?, ?)", "a", "M", "b", "F", "c", "F")
+
+	var users []User
+	err := conn.Table("users").
+		Select("id", "name").
+		Where("gender", "M").
+		Union(conn.Table("users").Select("id", "name").Where("name", "c")).
+		OrderByDesc("name").
+		Limit(1).
+		Get(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 1 || users[0].Name != "c" {
+		t.Errorf("Expected the union to return the user c but instead got %v !", users)
+	}
+
+	var count int64
+	conn.Table("users").Where("gender", "F").UnionAll(conn.Table("users").Where("gender", "F")).Count(&count)
+	if count != 4 {
+		t.Errorf("Expected the union all to count 4 users but instead got %d !", count)
+	}
+
+	b := conn.Table("users").
+		Select("id", "name").
+		Where("gender", "F").
+		Union(conn.Table("users").Select("id", "name").Where("name", "a"))
+
+	if err = b.Count(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected the union to count 3 users but instead got %d !", count)
+	}
+
+	users = nil
+	if err = b.Get(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("Expected the union to get 3 users after the count but instead got %v !", users)
+	}
+}
+
+func TestTableUnionOrderBindings(t *testing.T) {
+	b := NewBuilder(nil, grammar.NewMySqlGrammar("")).
+		From("users").
+		Where("a", 1).
+		Union(NewBuilder(nil, grammar.NewMySqlGrammar("")).From("users").Where("b", 2)).
+		OrderByRaw("field(id, ?)", 3)
+
+	expected := "(SELECT * FROM `users` WHERE `a` = ?) union (SELECT * FROM `users` WHERE `b` = ?) ORDER BY field(id, ?)"
+	if sql := b.ToSql(); sql != expected {
+		t.Errorf("Expected the union to be %s but instead got %s !", expected, sql)
+	}
+
+	if bindings := fmt.Sprint(b.GetBindings()); bindings != "[1 2 3]" {
+		t.Errorf("Expected the bindings to be [1 2 3] but instead got %s !", bindings)
+	}
+}
+
+func TestTableLock(t *testing.T) {
+	b := NewBuilder(nil, grammar.NewMySqlGrammar("")).From("jobs").Where("reserved", 0)
+
+	expected := "SELECT * FROM `jobs` WHERE `reserved` = ? FOR UPDATE SKIP LOCKED"
+	if sql := b.LockForUpdate(LockSkipLocked).ToSql(); sql != expected {
+		t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+	}
+
+	expected = "SELECT * FROM `jobs` WHERE `reserved` = ? LOCK IN SHARE MODE"
+	if sql := b.SharedLock().ToSql(); sql != expected {
+		t.Errorf("Expected the locked select to be %s but instead got %s !", expected, sql)
+	}
+}
+
+func TestTableWhereGroup(t *testing.T) {
+	b := NewBuilder(nil, grammar.NewMySqlGrammar("")).
+		From("users").
+		Where("a", 1).
+		WhereGroup(func(q *Builder) {
+			q.Where("b", 2).OrWhereGroup(func(q *Builder) {
+				q.Where("c", 3).Where("d", 4)
+			})
+		}).
+		Where("e", 5)
+
+	expected := "SELECT * FROM `users` WHERE `a` = ? and (`b` = ? or (`c` = ? and `d` = ?)) and `e` = ?"
+	if sql := b.ToSql(); sql != expected {
+		t.Errorf("Expected the grouped select to be %s but instead got %s !", expected, sql)
+	}
+
+	if bindings := fmt.Sprint(b.GetBindings()); bindings != "[1 2 3 4 5]" {
+		t.Errorf("Expected the bindings to be [1 2 3 4 5] but instead got %s !", bindings)
+	}
+}
+
+func TestTableSubqueries(t *testing.T) {
+	g := grammar.NewPostgresGrammar("")
+	orders := NewBuilder(nil, g).From("orders").Where("status", "paid")
+
+	b := NewBuilder(nil, g).
+		Select("id").
+		SelectSub(NewBuilder(nil, g).From("orders").SelectRaw("count(*)").WhereColumn("orders.user_id", "u.id"), "total").
+		FromSub(NewBuilder(nil, g).From("users").Where("active", true), "u").
+		WhereInSub("id", orders.Select("user_id")).
+		WhereNotExists(NewBuilder(nil, g).From("bans").Where("reason", "spam")).
+		Where("name", "a")
+
+	expected := `SELECT "id", (SELECT count(*) FROM "orders" WHERE "orders"."user_id" = "u"."id") as "total" FROM (SELECT * FROM "users" WHERE "active" = $1) as "u" WHERE "id" IN (SELECT "user_id" FROM "orders" WHERE "status" = $2) and NOT EXISTS (SELECT * FROM "bans" WHERE "reason" = $3) and "name" = $4`
+	if sql := b.ToSql(); sql != expected {
+		t.Errorf("Expected the select to be %s but instead got %s !", expected, sql)
+	}
+
+	if bindings := fmt.Sprint(b.GetBindings()); bindings != "[true paid spam a]" {
+		t.Errorf("Expected the bindings to be [true paid spam a] but instead got %s !", bindings)
+	}
+}
+
+type Account struct {
+	Id      int64  `torm:"primary_key;column:id"`
+	Name    string `torm:"column:name"`
+	Email   string
+	Balance float64
+	Note    string `torm:"-"`
+}
+
+func (a *Account) TableName() string {
+	return "accounts"
+}
+
+func TestTableStructs(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, email TEXT DEFAULT 'none', balance REAL)")
+
+	account := &Account{Name: "a", Balance: 10, Note: "ignored"}
+	if _, _, err := conn.Table("accounts").OmitBlank().InsertStruct(account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Id != 1 {
+		t.Errorf("Expected the inserted id to be 1 but instead got %d !", account.Id)
+	}
+
+	_, affected, err := conn.Table("accounts").InsertStructs([]Account{{Name: "b"}, {Name: "c", Email: "c@example.com"}})
+	if err != nil || affected != 2 {
+		t.Fatalf("Expected 2 inserted accounts but instead got %d, %v !", affected, err)
+	}
+
+	if _, _, err = conn.Table("accounts").OmitBlank().InsertStruct(&Account{}); err == nil {
+		t.Error("Expect: inserting a blank struct without its blank fields should fail")
+	}
+	if _, _, err = conn.Table("accounts").OmitBlank().InsertStructs([]Account{{}, {}}); err == nil {
+		t.Error("Expect: inserting blank structs without their blank fields should fail")
+	}
+
+	account.Name = "z"
+	account.Balance = 0
+	if _, err = conn.Table("accounts").UpdateStruct(account, "name"); err != nil {
+		t.Fatal(err)
+	}
+
+	var accounts []Account
+	conn.Table("accounts").OrderBy("id").Get(&accounts)
+
+	if len(accounts) != 3 {
+		t.Fatalf("Expected 3 accounts but instead got %v !", accounts)
+	}
+	if accounts[0].Name != "z" || accounts[0].Email != "none" || accounts[0].Balance != 10 {
+		t.Errorf("Expected the first account to be updated but instead got %v !", accounts[0])
+	}
+	if accounts[1].Email != "" || accounts[2].Email != "c@example.com" {
+		t.Errorf("Expected the bulk inserted emails but instead got %v !", accounts[1:])
+	}
+}
+
+func TestTableUpsert(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE products (id INTEGER PRIMARY KEY, sku TEXT UNIQUE, price REAL)")
+
+	_, err := conn.Table("products").Upsert([]map[string]interface{}{{"sku": "a", "price": 1}}, []string{"sku"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conn.Table("products").Upsert(
+		[]map[string]interface{}{{"sku": "a", "price": 2}, {"sku": "b", "price": 3}},
+		[]string{"sku"},
+		[]string{"price"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = conn.Table("products").Upsert([]map[string]interface{}{{"sku": "c", "price": 4}}, nil, nil); err == nil {
+		t.Error("Expect: the upsert without unique columns should fail")
+	}
+
+	affected, err := conn.Table("products").InsertOrIgnore([]map[string]interface{}{{"sku": "b", "price": 9}})
+	if err != nil || affected != 0 {
+		t.Errorf("Expected the duplicated product to be ignored but instead got %d, %v !", affected, err)
+	}
+
+	var total float64
+	conn.Table("products").Sum("price", &total)
+	if total != 5 {
+		t.Errorf("Expected the total price to be 5 but instead got %v !", total)
+	}
+}
+
+func TestTableInsertBatch(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, email TEXT, balance REAL)")
+
+	var values []map[string]interface{}
+	for i := 0; i < 1000; i++ {
+		values = append(values, map[string]interface{}{"name": fmt.Sprint(i), "balance": i})
+	}
+
+	conn.EnableQueryLog()
+	result, err := conn.Table("accounts").InsertBatch(values, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RowsAffected != 1000 || result.FirstInsertId != 1 || result.LastInsertId != 1000 {
+		t.Errorf("Expected 1000 rows with the ids 1 to 1000 but instead got %+v !", result)
+	}
+
+	// 999 place-holders fit 499 rows of 2 columns, so 3 inserts are expected.
+	if log := conn.GetQueryLog(); len(log) != 3 {
+		t.Errorf("Expected 3 inserts but instead got %d queries !", len(log))
+	}
+
+	result, err = conn.Table("accounts").InsertStructsBatch([]Account{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RowsAffected != 3 || result.FirstInsertId != 1001 || result.LastInsertId != 1003 {
+		t.Errorf("Expected 3 rows with the ids 1001 to 1003 but instead got %+v !", result)
+	}
+
+	_, err = conn.Table("accounts").InsertBatch([]map[string]interface{}{{"name": "x"}, {"missing": "y"}}, 1)
+	if err == nil {
+		t.Error("Expect: the invalid batch should fail")
+	}
+
+	_, err = conn.Table("accounts").InsertBatch([]map[string]interface{}{{}, {}}, 0)
+	if err == nil {
+		t.Error("Expect: the batch without columns should fail")
+	}
+
+	var count int64
+	conn.Table("accounts").Count(&count)
+	if count != 1003 {
+		t.Errorf("Expected the failed batch to be rolled back but instead counted %d accounts !", count)
+	}
+}
+
+// rowsLimitedGrammar A SQLite grammar limiting the rows of the inserts, as SQL
+// Server does.
+type rowsLimitedGrammar struct {
+	*grammar.SQLiteGrammar
+}
+
+func (g rowsLimitedGrammar) MaxInsertRows() int {
+	return 100
+}
+
+func TestTableInsertBatchMaxRows(t *testing.T) {
+	conn := openSQLite(t)
+	conn.SetQueryGrammar(rowsLimitedGrammar{grammar.NewSQLiteGrammar("")})
+	conn.Statement("CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT)")
+
+	var values []map[string]interface{}
+	for i := 0; i < 250; i++ {
+		values = append(values, map[string]interface{}{"name": fmt.Sprint(i)})
+	}
+
+	conn.EnableQueryLog()
+	result, err := conn.Table("accounts").InsertBatch(values, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RowsAffected != 250 {
+		t.Errorf("Expected 250 rows but instead got %+v !", result)
+	}
+
+	// 999 place-holders fit 999 rows of 1 column, but the grammar allows 100.
+	if log := conn.GetQueryLog(); len(log) != 3 {
+		t.Errorf("Expected 3 inserts but instead got %d queries !", len(log))
+	}
+}
+
+func TestTableInsertUsing(t *testing.T) {
+	conn := openSQLite(t,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, active INTEGER)",
+		"CREATE TABLE archived_users (id INTEGER PRIMARY KEY, name TEXT)",
+	)
+	conn.Insert("INSERT INTO users (name, active) VALUES (?, ?), (?, ?), (?, ?)", "a", 1, "b", 0, "c", 0)
+
+	affected, err := conn.Table("archived_users").InsertUsing(
+		[]string{"id", "name"},
+		conn.Table("users").Select("id", "name").Where("active", 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if affected != 2 {
+		t.Errorf("Expected 2 archived users but instead got %d !", affected)
+	}
+}
+
+func TestModelCreateGeneratesId(t *testing.T) {
+	conn := openSQLite(t, "CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, email TEXT, balance REAL)")
+
+	for i := int64(1); i <= 2; i++ {
+		account := &Account{Name: "a"}
+		if err := conn.Create(account); err != nil {
+			t.Fatal(err)
+		}
+		if account.Id != i {
+			t.Errorf("Expected the created id to be %d but instead got %d !", i, account.Id)
+		}
+	}
+}